@@ -9,7 +9,8 @@ type Config struct {
 	Server   ServerConfig
 	Database DBConfig
 	Redis    RedisConfig
-	OAuth    OAuthConfig
+	OAuth    []OAuthConfig
+	Content  ContentConfig
 }
 
 type ServerConfig struct {
@@ -30,12 +31,29 @@ type RedisConfig struct {
 	CookieStoreSecret string
 }
 
+// OAuthConfig is one login provider.  Name identifies it in routes and linked identities, it defaults to the
+// lowercase Provider so several GitHub apps, for example, can be told apart.
 type OAuthConfig struct {
 	Provider    string
+	Name        string
+	DisplayName string
 	Id          string
 	Secret      string
 	RedirectUrl string
 	Scopes      []string
+
+	// OpenID Connect only.  The claims default to sub, preferred_username and picture.
+	Issuer        string
+	UserIdClaim   string
+	UserNameClaim string
+	AvatarClaim   string
+}
+
+// ContentConfig sets up the word list filter for team and display names
+type ContentConfig struct {
+	BlockedWords     []string
+	BlockedWordsFile string // one word per line, lines starting with # are ignored
+	ReservedWords    []string
 }
 
 func (config *Config) LoadFromFile(filename string) {
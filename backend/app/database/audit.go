@@ -0,0 +1,65 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Audit Target Types
+const (
+	AuditTargetTeam = "TEAM"
+)
+
+// Audit Actions
+const (
+	AuditTeamRename       = "TEAM_RENAME"
+	AuditTeamEdit         = "TEAM_EDIT"
+	AuditTeamLock         = "TEAM_LOCK"
+	AuditTeamUnlock       = "TEAM_UNLOCK"
+	AuditTeamDisband      = "TEAM_DISBAND"
+	AuditTeamRemoveMember = "TEAM_REMOVE_MEMBER"
+	AuditTeamRosterOpen   = "TEAM_ROSTER_OPEN"
+)
+
+type DBAuditEntry struct {
+	Id          pgtype.UUID        `db:"id"`
+	ActorUserId pgtype.UUID        `db:"actor_user_id"`
+	TargetType  string             `db:"target_type"`
+	TargetId    pgtype.UUID        `db:"target_id"`
+	EventId     pgtype.UUID        `db:"event_id"`
+	Action      string             `db:"action"`
+	Reason      string             `db:"reason"`
+	Details     string             `db:"details"`
+	CreatedOn   pgtype.Timestamptz `db:"created_on"`
+}
+
+// DBAuditEntryInfo includes the display name of the user who took the action
+type DBAuditEntryInfo struct {
+	DBAuditEntry
+	ActorDisplayName *string `db:"actor_display_name"`
+}
+
+// CreateAuditEntryTx records an action as part of the transaction that takes it, so actions are never taken without
+// their record.
+func CreateAuditEntryTx(tx Querier, entry DBAuditEntry) (DBAuditEntry, error) {
+	entry, err := GetRowTx[DBAuditEntry](tx,
+		`INSERT INTO audit_log (actor_user_id, target_type, target_id, event_id, action, reason, details)
+         VALUES ($1, $2, $3, $4, $5, $6, $7)
+         RETURNING *`,
+		entry.ActorUserId, entry.TargetType, entry.TargetId, entry.EventId, entry.Action, entry.Reason, entry.Details)
+	if err != nil {
+		logger.Error("CreateAuditEntry error: %v", err)
+	}
+	return entry, err
+}
+
+// GetAuditEntries returns the audit trail of a target, newest first.
+func GetAuditEntries(targetType string, targetId pgtype.UUID) ([]DBAuditEntryInfo, error) {
+	entries, err := GetRows[DBAuditEntryInfo](
+		`SELECT a.*, u.display_name AS actor_display_name
+         FROM audit_log a
+         LEFT JOIN users u ON (u.id = a.actor_user_id)
+         WHERE a.target_type = $1 AND a.target_id = $2
+         ORDER BY a.created_on DESC`,
+		targetType, targetId)
+	return entries, err
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AvailabilityWindow is a weekly time range in the owner's timezone.  Day is 0 for Sunday through 6 for Saturday,
+// Start and End are "15:04" clock times with End allowed to be "24:00".
+type AvailabilityWindow struct {
+	Day   int
+	Start string
+	End   string
+}
+
+type DBUserAvailability struct {
+	UserId    pgtype.UUID          `db:"user_id"`
+	Timezone  string               `db:"timezone"`
+	Windows   []AvailabilityWindow `db:"windows"`
+	UpdatedOn pgtype.Timestamptz   `db:"updated_on"`
+}
+
+// DBMemberAvailability is a team member's availability, Timezone is empty and Windows is empty when they haven't
+// set one.
+type DBMemberAvailability struct {
+	UserId      pgtype.UUID          `db:"user_id"`
+	DisplayName string               `db:"display_name"`
+	Timezone    string               `db:"timezone"`
+	Windows     []AvailabilityWindow `db:"windows"`
+}
+
+func SaveUserAvailability(availability DBUserAvailability) (DBUserAvailability, error) {
+	availability, err := GetRow[DBUserAvailability](
+		`INSERT INTO user_availability (user_id, timezone, windows)
+         VALUES ($1, $2, $3)
+         ON CONFLICT (user_id)
+         DO UPDATE
+         SET timezone = $2, windows = $3, updated_on = now()
+         RETURNING *`,
+		availability.UserId, availability.Timezone, availability.Windows)
+	if err != nil {
+		logger.Error("SaveUserAvailability error: %v", err)
+	}
+	return availability, err
+}
+
+func GetUserAvailability(userId pgtype.UUID) (DBUserAvailability, error) {
+	availability, err := GetRow[DBUserAvailability](
+		`SELECT * FROM user_availability WHERE user_id = $1`,
+		userId)
+	return availability, err
+}
+
+// GetTeamAvailability returns the availability of every member of the team.
+func GetTeamAvailability(teamId pgtype.UUID) ([]DBMemberAvailability, error) {
+	members, err := GetRows[DBMemberAvailability](
+		`SELECT u.id AS user_id,
+                u.display_name,
+                COALESCE(ua.timezone, '') AS timezone,
+                COALESCE(ua.windows, '[]'::jsonb) AS windows
+         FROM team_members tm
+         INNER JOIN users u ON (u.id = tm.user_id)
+         LEFT JOIN user_availability ua ON (ua.user_id = tm.user_id)
+         WHERE tm.team_id = $1
+         ORDER BY tm.created_on, u.id`,
+		teamId)
+	if err != nil {
+		logger.Error("GetTeamAvailability error: %v", err)
+	}
+	return members, err
+}
@@ -13,11 +13,24 @@ type DBEvent struct {
 	Timeline        string           `db:"timeline"`
 	OrganizerUserId pgtype.UUID      `db:"organizer_user_id" json:"-"`
 	MaxTeams        int              `db:"max_teams"`
+	MaxTeamSize     int              `db:"max_team_size"` // 0 or less means no limit
 	StartsAt        pgtype.Timestamp `db:"starts_at"`
 	EndsAt          pgtype.Timestamp `db:"ends_at"`
 	CreatedOn       pgtype.Timestamp `db:"created_on" json:"-"`
+	// lets users be on more than one team in the event
+	AllowMultipleTeams bool `db:"allow_multiple_teams"`
+	// when team rosters lock once the event has started, see the RosterLock constants
+	RosterLockPolicy string `db:"roster_lock_policy"`
+	RosterLockHours  int    `db:"roster_lock_hours"`
 }
 
+// Roster Lock Policies
+const (
+	RosterLockAtStart    = "AT_START"
+	RosterLockAfterHours = "AFTER_HOURS"
+	RosterLockNever      = "NEVER"
+)
+
 type DBEventStatus struct {
 	Id          int    `db:"id"`
 	Code        string `db:"code"`
@@ -90,10 +103,15 @@ func UpdateEvent(event DBEvent) (DBEvent, error) {
              rules=$6,
              max_teams=$7,
              starts_at=$8,
-             ends_at=$9
+             ends_at=$9,
+             max_team_size=$10,
+             allow_multiple_teams=$11,
+             roster_lock_policy=$12,
+             roster_lock_hours=$13
          WHERE id=$1
          RETURNING *`,
-		event.Id, event.StatusId, event.Title, event.Timeline, event.Description, event.Rules, event.MaxTeams, event.StartsAt, event.EndsAt)
+		event.Id, event.StatusId, event.Title, event.Timeline, event.Description, event.Rules, event.MaxTeams, event.StartsAt, event.EndsAt,
+		event.MaxTeamSize, event.AllowMultipleTeams, event.RosterLockPolicy, event.RosterLockHours)
 	return event, err
 }
 
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DBUserIdentity is an OAuth provider account a user can log in with
+type DBUserIdentity struct {
+	Id              pgtype.UUID        `db:"id"`
+	UserId          pgtype.UUID        `db:"user_id"`
+	ServiceName     string             `db:"service_name"`
+	ServiceUserId   string             `db:"service_user_id"`
+	ServiceUserName string             `db:"service_user_name"`
+	AvatarUrl       *string            `db:"avatar_url"`
+	CreatedOn       pgtype.Timestamptz `db:"created_on"`
+}
+
+// UserIdentityConstraint is reported by LinkUserIdentity when the provider account already belongs to a user
+const UserIdentityConstraint = "idx_user_identity"
+
+// LoginWithIdentity returns the user owning the provider account, refreshing the account's name and avatar.
+// A new user named displayName is created the first time an account logs in.
+func LoginWithIdentity(identity DBUserIdentity, displayName string) (DBUser, error) {
+	var user DBUser
+	err := WithTransaction(func(tx pgx.Tx) error {
+		rows, err := tx.Query(context.Background(),
+			`UPDATE user_identities
+             SET service_user_name = $3, avatar_url = $4
+             WHERE service_name = $1 AND service_user_id = $2
+             RETURNING *`,
+			identity.ServiceName, identity.ServiceUserId, identity.ServiceUserName, identity.AvatarUrl)
+		if err != nil {
+			return err
+		}
+		existing, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[DBUserIdentity])
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			rows, err = tx.Query(context.Background(),
+				`INSERT INTO users (service_name, service_user_id, service_user_name, display_name, avatar_url)
+                 VALUES ($1, $2, $3, $4, $5)
+                 RETURNING *`,
+				identity.ServiceName, identity.ServiceUserId, identity.ServiceUserName, displayName, identity.AvatarUrl)
+			if err != nil {
+				return err
+			}
+			if user, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[DBUser]); err != nil {
+				return err
+			}
+			_, err = tx.Exec(context.Background(),
+				`INSERT INTO user_identities (user_id, service_name, service_user_id, service_user_name, avatar_url)
+                 VALUES ($1, $2, $3, $4, $5)`,
+				user.Id, identity.ServiceName, identity.ServiceUserId, identity.ServiceUserName, identity.AvatarUrl)
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		// the users columns mirror the primary identity, only refresh them when this is it
+		rows, err = tx.Query(context.Background(),
+			`UPDATE users
+             SET service_user_name = CASE WHEN service_name = $2 AND service_user_id = $3
+                                          THEN $4 ELSE service_user_name END,
+                 avatar_url = CASE WHEN service_name = $2 AND service_user_id = $3
+                                   THEN $5 ELSE avatar_url END
+             WHERE id = $1
+             RETURNING *`,
+			existing.UserId, identity.ServiceName, identity.ServiceUserId, identity.ServiceUserName, identity.AvatarUrl)
+		if err != nil {
+			return err
+		}
+		user, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[DBUser])
+		return err
+	})
+	if err != nil {
+		logger.Error("LoginWithIdentity error: %v", err)
+	}
+	return user, err
+}
+
+// LinkUserIdentity adds a provider account to the user.  Fails with a UserIdentityConstraint unique violation if the
+// account is already linked, to this user or another one.
+func LinkUserIdentity(userId pgtype.UUID, identity DBUserIdentity) (DBUserIdentity, error) {
+	identity, err := GetRow[DBUserIdentity](
+		`INSERT INTO user_identities (user_id, service_name, service_user_id, service_user_name, avatar_url)
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING *`,
+		userId, identity.ServiceName, identity.ServiceUserId, identity.ServiceUserName, identity.AvatarUrl)
+	return identity, err
+}
+
+func GetUserIdentities(userId pgtype.UUID) ([]DBUserIdentity, error) {
+	identities, err := GetRows[DBUserIdentity](
+		`SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_on, id`,
+		userId)
+	return identities, err
+}
+
+// UnlinkUserIdentity removes a provider account from the user, as long as they keep at least one to log in with.
+// pgx.ErrNoRows is returned when the identity isn't the user's or is their last one.  When the primary identity is
+// removed, the oldest remaining one takes its place.
+func UnlinkUserIdentity(userId pgtype.UUID, identityId pgtype.UUID) (DBUserIdentity, error) {
+	var removed DBUserIdentity
+	err := WithTransaction(func(tx pgx.Tx) error {
+		// lock the user so two unlinks can't remove the last two identities together
+		_, err := tx.Exec(context.Background(), `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userId)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(context.Background(),
+			`DELETE FROM user_identities
+             WHERE id = $2 AND user_id = $1
+               AND (SELECT COUNT(*) FROM user_identities WHERE user_id = $1) > 1
+             RETURNING *`,
+			userId, identityId)
+		if err != nil {
+			return err
+		}
+		if removed, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[DBUserIdentity]); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(),
+			`UPDATE users
+             SET service_name = primary_identity.service_name,
+                 service_user_id = primary_identity.service_user_id,
+                 service_user_name = primary_identity.service_user_name,
+                 avatar_url = primary_identity.avatar_url
+             FROM (
+               SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_on, id LIMIT 1
+             ) AS primary_identity
+             WHERE users.id = $1 AND users.service_name = $2 AND users.service_user_id = $3`,
+			userId, removed.ServiceName, removed.ServiceUserId)
+		return err
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("UnlinkUserIdentity error: %v", err)
+	}
+	return removed, err
+}
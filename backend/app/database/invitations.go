@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Team Invitation Statuses
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationDeclined = "DECLINED"
+	InvitationExpired  = "EXPIRED"
+)
+
+type DBTeamInvitation struct {
+	Id              pgtype.UUID        `db:"id"`
+	TeamId          pgtype.UUID        `db:"team_id"`
+	InviteeUserId   pgtype.UUID        `db:"invitee_user_id"`
+	InvitedByUserId pgtype.UUID        `db:"invited_by_user_id"`
+	Status          string             `db:"status"`
+	ExpiresAt       pgtype.Timestamptz `db:"expires_at"`
+	RespondedOn     pgtype.Timestamptz `db:"responded_on"`
+	CreatedOn       pgtype.Timestamptz `db:"created_on"`
+}
+
+// DBTeamInvitationInfo includes the names shown to the invitee and the team
+type DBTeamInvitationInfo struct {
+	DBTeamInvitation
+	TeamName      string  `db:"team_name"`
+	InviteeName   string  `db:"invitee_name"`
+	InvitedByName *string `db:"invited_by_name"`
+}
+
+// CreateTeamInvitation inserts a pending invitation.  An expired invitation of the invitee to the same team is
+// marked expired first, since it would otherwise still count as the pending one.
+func CreateTeamInvitation(invitation DBTeamInvitation) (DBTeamInvitation, error) {
+	err := WithTransaction(func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(),
+			`UPDATE team_invitations
+             SET status = $3
+             WHERE team_id = $1 AND invitee_user_id = $2 AND status = $4 AND expires_at <= now()`,
+			invitation.TeamId, invitation.InviteeUserId, InvitationExpired, InvitationPending)
+		if err != nil {
+			return err
+		}
+		invitation, err = GetRowTx[DBTeamInvitation](tx,
+			`INSERT INTO team_invitations (team_id, invitee_user_id, invited_by_user_id, expires_at)
+             VALUES ($1, $2, $3, $4)
+             RETURNING *`,
+			invitation.TeamId, invitation.InviteeUserId, invitation.InvitedByUserId, invitation.ExpiresAt)
+		return err
+	})
+	return invitation, err
+}
+
+func GetTeamInvitation(invitationId pgtype.UUID) (DBTeamInvitation, error) {
+	invitation, err := GetRow[DBTeamInvitation](
+		`SELECT * FROM team_invitations WHERE id = $1`,
+		invitationId)
+	return invitation, err
+}
+
+// invitationInfoQuery reports pending invitations past expires_at as expired, whether or not their row was updated
+const invitationInfoQuery = `SELECT ti.id, ti.team_id, ti.invitee_user_id, ti.invited_by_user_id,
+                CASE WHEN ti.status = 'PENDING' AND ti.expires_at <= now() THEN 'EXPIRED' ELSE ti.status END AS status,
+                ti.expires_at, ti.responded_on, ti.created_on,
+                t.name AS team_name,
+                invitee.display_name AS invitee_name,
+                inviter.display_name AS invited_by_name
+         FROM team_invitations ti
+         INNER JOIN teams t ON (t.id = ti.team_id)
+         INNER JOIN users invitee ON (invitee.id = ti.invitee_user_id)
+         LEFT JOIN users inviter ON (inviter.id = ti.invited_by_user_id)`
+
+// GetUserInvitations returns the invitations addressed to the user, newest first.  Only pending ones are returned
+// unless all is set.
+func GetUserInvitations(userId pgtype.UUID, all bool) ([]DBTeamInvitationInfo, error) {
+	invitations, err := GetRows[DBTeamInvitationInfo](
+		invitationInfoQuery+`
+         WHERE ti.invitee_user_id = $1 AND ($2 OR (ti.status = $3 AND ti.expires_at > now()))
+         ORDER BY ti.created_on DESC`,
+		userId, all, InvitationPending)
+	return invitations, err
+}
+
+// GetTeamInvitations returns the team's pending invitations, oldest first.
+func GetTeamInvitations(teamId pgtype.UUID) ([]DBTeamInvitationInfo, error) {
+	invitations, err := GetRows[DBTeamInvitationInfo](
+		invitationInfoQuery+`
+         WHERE ti.team_id = $1 AND ti.status = $2 AND ti.expires_at > now()
+         ORDER BY ti.created_on`,
+		teamId, InvitationPending)
+	return invitations, err
+}
+
+// RespondToTeamInvitation moves a pending, unexpired invitation to the given status.  pgx.ErrNoRows is returned
+// when the invitation is no longer pending.
+func RespondToTeamInvitation(invitationId pgtype.UUID, status string) (DBTeamInvitation, error) {
+	return RespondToTeamInvitationTx(Pool, invitationId, status)
+}
+
+func RespondToTeamInvitationTx(tx Querier, invitationId pgtype.UUID, status string) (DBTeamInvitation, error) {
+	invitation, err := GetRowTx[DBTeamInvitation](tx,
+		`UPDATE team_invitations
+         SET status = $2, responded_on = now()
+         WHERE id = $1 AND status = $3 AND expires_at > now()
+         RETURNING *`,
+		invitationId, status, InvitationPending)
+	return invitation, err
+}
+
+// AcceptTeamInvitation accepts a pending invitation and, with addMember, adds the invitee to the team in the same
+// transaction, so the invitation stays pending if they can't join.  pgx.ErrNoRows is returned if the invitation is
+// no longer pending, AddTeamMember's errors if the invitee can't join.
+func AcceptTeamInvitation(invitationId pgtype.UUID, addMember bool) (DBTeamInvitation, error) {
+	var invitation DBTeamInvitation
+	err := WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		invitation, err = RespondToTeamInvitationTx(tx, invitationId, InvitationAccepted)
+		if err != nil || !addMember {
+			return err
+		}
+		_, err = AddTeamMemberTx(tx, invitation.InviteeUserId, invitation.TeamId, TeamRoleMember)
+		return err
+	})
+	return invitation, err
+}
+
+// DeleteTeamInvitation revokes an invitation of the team.
+func DeleteTeamInvitation(teamId pgtype.UUID, invitationId pgtype.UUID) (DBTeamInvitation, error) {
+	invitation, err := GetRow[DBTeamInvitation](
+		`DELETE FROM team_invitations
+         WHERE id = $1 AND team_id = $2
+         RETURNING *`,
+		invitationId, teamId)
+	return invitation, err
+}
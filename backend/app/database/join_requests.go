@@ -0,0 +1,94 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Join Request Statuses
+const (
+	JoinRequestPending  = "PENDING"
+	JoinRequestApproved = "APPROVED"
+	JoinRequestDeclined = "DECLINED"
+)
+
+type DBJoinRequest struct {
+	Id              pgtype.UUID        `db:"id"`
+	TeamId          pgtype.UUID        `db:"team_id"`
+	UserId          pgtype.UUID        `db:"user_id"`
+	Message         string             `db:"message"`
+	Status          string             `db:"status"`
+	DecidedByUserId pgtype.UUID        `db:"decided_by_user_id" json:"-"`
+	DecidedOn       pgtype.Timestamptz `db:"decided_on"`
+	CreatedOn       pgtype.Timestamptz `db:"created_on"`
+}
+
+// DBJoinRequestInfo includes the requester's display name for the team owner
+type DBJoinRequestInfo struct {
+	DBJoinRequest
+	DisplayName string `db:"display_name"`
+}
+
+func CreateJoinRequest(teamId pgtype.UUID, userId pgtype.UUID, message string) (DBJoinRequest, error) {
+	request, err := GetRow[DBJoinRequest](
+		`INSERT INTO team_join_requests (team_id, user_id, message)
+         VALUES ($1, $2, $3)
+         RETURNING *`,
+		teamId, userId, message)
+	return request, err
+}
+
+func GetJoinRequest(teamId pgtype.UUID, requestId pgtype.UUID) (DBJoinRequest, error) {
+	request, err := GetRow[DBJoinRequest](
+		`SELECT * FROM team_join_requests WHERE id = $1 AND team_id = $2`,
+		requestId, teamId)
+	return request, err
+}
+
+func GetPendingJoinRequests(teamId pgtype.UUID) ([]DBJoinRequestInfo, error) {
+	requests, err := GetRows[DBJoinRequestInfo](
+		`SELECT jr.*, u.display_name
+         FROM team_join_requests jr
+         INNER JOIN users u ON (u.id = jr.user_id)
+         WHERE jr.team_id = $1 AND jr.status = $2
+         ORDER BY jr.created_on`,
+		teamId, JoinRequestPending)
+	return requests, err
+}
+
+// DecideJoinRequest moves a pending request to the given status.  pgx.ErrNoRows is returned if the request
+// doesn't exist for the team or was already decided.
+func DecideJoinRequest(teamId pgtype.UUID, requestId pgtype.UUID, status string, deciderId pgtype.UUID) (DBJoinRequest, error) {
+	return DecideJoinRequestTx(Pool, teamId, requestId, status, deciderId)
+}
+
+func DecideJoinRequestTx(tx Querier, teamId pgtype.UUID, requestId pgtype.UUID, status string,
+	deciderId pgtype.UUID) (DBJoinRequest, error) {
+	request, err := GetRowTx[DBJoinRequest](tx,
+		`UPDATE team_join_requests
+         SET status = $3,
+             decided_by_user_id = $4,
+             decided_on = now()
+         WHERE id = $1 AND team_id = $2 AND status = 'PENDING'
+         RETURNING *`,
+		requestId, teamId, status, deciderId)
+	return request, err
+}
+
+// ApproveJoinRequest approves a pending request and, with addMember, adds the requester to the team in the same
+// transaction, so the request stays pending if they can't join.  pgx.ErrNoRows is returned if the request was
+// already decided, AddTeamMember's errors if the requester can't join.
+func ApproveJoinRequest(teamId pgtype.UUID, requestId pgtype.UUID, deciderId pgtype.UUID,
+	addMember bool) (DBJoinRequest, error) {
+	var request DBJoinRequest
+	err := WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		request, err = DecideJoinRequestTx(tx, teamId, requestId, JoinRequestApproved, deciderId)
+		if err != nil || !addMember {
+			return err
+		}
+		_, err = AddTeamMemberTx(tx, request.UserId, teamId, TeamRoleMember)
+		return err
+	})
+	return request, err
+}
@@ -0,0 +1,5 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS invite_uses;
+ALTER TABLE teams DROP COLUMN IF EXISTS invite_max_uses;
+ALTER TABLE teams DROP COLUMN IF EXISTS invite_expires_at;
+
+DROP INDEX IF EXISTS idx_team_invite_code;
@@ -0,0 +1,7 @@
+-- invite codes must be unique so a code always resolves to a single team
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_invite_code ON teams (invite_code);
+
+-- optional limits on how long and how often an invite code can be used
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS invite_expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS invite_max_uses INTEGER;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS invite_uses INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS team_join_requests (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    team_id UUID NOT NULL references teams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    decided_by_user_id UUID references users(id) ON DELETE SET NULL,
+    decided_on TIMESTAMP WITH TIME ZONE,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+-- a user can only have one open request per team
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_join_request_pending ON team_join_requests (team_id, user_id) WHERE status = 'PENDING';
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    link TEXT NOT NULL DEFAULT '',
+    read_on TIMESTAMP WITH TIME ZONE,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_on);
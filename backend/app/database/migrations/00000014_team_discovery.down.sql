@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_teams_event;
+
+ALTER TABLE events DROP COLUMN IF EXISTS max_team_size;
+
+ALTER TABLE teams DROP COLUMN IF EXISTS wanted_roles;
+ALTER TABLE teams DROP COLUMN IF EXISTS looking_for_members;
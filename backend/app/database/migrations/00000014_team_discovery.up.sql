@@ -0,0 +1,8 @@
+-- teams advertise that they're recruiting and which roles they're after
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS looking_for_members BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS wanted_roles TEXT[] NOT NULL DEFAULT '{}';
+
+-- maximum number of members per team, 0 or less means no limit
+ALTER TABLE events ADD COLUMN IF NOT EXISTS max_team_size INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_teams_event ON teams (event_id);
@@ -0,0 +1,14 @@
+-- participants without a team describe themselves so they can be matched with teams and each other
+CREATE TABLE IF NOT EXISTS solo_profiles (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL references events(id) ON DELETE CASCADE,
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    timezone TEXT NOT NULL DEFAULT '',
+    availability TEXT NOT NULL DEFAULT '',
+    technologies TEXT[] NOT NULL DEFAULT '{}',
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc')),
+    updated_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_solo_profile_user_event ON solo_profiles (user_id, event_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_one_team_per_event ON team_members;
+DROP FUNCTION IF EXISTS enforce_one_team_per_event();
+
+ALTER TABLE events DROP COLUMN IF EXISTS allow_multiple_teams;
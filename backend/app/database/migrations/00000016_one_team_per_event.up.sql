@@ -0,0 +1,42 @@
+-- organizers can allow users to be on more than one team in an event
+ALTER TABLE events ADD COLUMN IF NOT EXISTS allow_multiple_teams BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- team_members has no event_id, so one team per user per event is enforced with a trigger rather than a unique index
+CREATE OR REPLACE FUNCTION enforce_one_team_per_event() RETURNS trigger AS $$
+DECLARE
+    team_event_id UUID;
+    multiple_allowed BOOLEAN;
+BEGIN
+    SELECT t.event_id, e.allow_multiple_teams
+    INTO team_event_id, multiple_allowed
+    FROM teams t
+    INNER JOIN events e ON (e.id = t.event_id)
+    WHERE t.id = NEW.team_id;
+
+    IF multiple_allowed THEN
+        RETURN NEW;
+    END IF;
+
+    -- serialize membership changes per user so two concurrent joins can't both pass the check
+    PERFORM pg_advisory_xact_lock(hashtext('team_members:' || NEW.user_id::text));
+
+    IF EXISTS (
+        SELECT 1
+        FROM team_members tm
+        INNER JOIN teams t ON (t.id = tm.team_id)
+        WHERE tm.user_id = NEW.user_id
+          AND t.event_id = team_event_id
+          AND tm.id <> NEW.id
+    ) THEN
+        RAISE EXCEPTION 'user % is already on a team in event %', NEW.user_id, team_event_id
+            USING ERRCODE = 'unique_violation', CONSTRAINT = 'one_team_per_event';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_one_team_per_event ON team_members;
+CREATE TRIGGER trg_one_team_per_event
+    BEFORE INSERT OR UPDATE OF user_id, team_id ON team_members
+    FOR EACH ROW EXECUTE FUNCTION enforce_one_team_per_event();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE teams DROP COLUMN IF EXISTS locked;
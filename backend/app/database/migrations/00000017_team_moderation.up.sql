@@ -0,0 +1,16 @@
+-- a locked team can only be changed by organizers
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- record of moderation actions.  target_id has no foreign key so entries outlive disbanded teams
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    actor_user_id UUID references users(id) ON DELETE SET NULL,
+    target_type TEXT NOT NULL,
+    target_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id, created_on);
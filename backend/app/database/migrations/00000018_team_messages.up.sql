@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS team_messages (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    team_id UUID NOT NULL references teams(id) ON DELETE CASCADE,
+    user_id UUID references users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    edited_on TIMESTAMP WITH TIME ZONE,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_messages_team ON team_messages (team_id, pinned, created_on);
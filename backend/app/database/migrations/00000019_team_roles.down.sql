@@ -0,0 +1 @@
+ALTER TABLE team_members DROP CONSTRAINT IF EXISTS team_members_role_check;
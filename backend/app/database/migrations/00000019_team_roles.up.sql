@@ -0,0 +1,5 @@
+-- only known roles are allowed, anything else becomes a plain member
+UPDATE team_members SET team_role = 'member' WHERE team_role NOT IN ('owner', 'co-owner', 'member');
+
+ALTER TABLE team_members DROP CONSTRAINT IF EXISTS team_members_role_check;
+ALTER TABLE team_members ADD CONSTRAINT team_members_role_check CHECK (team_role IN ('owner', 'co-owner', 'member'));
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_teams_event_name;
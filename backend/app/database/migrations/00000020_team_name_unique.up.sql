@@ -0,0 +1,10 @@
+-- existing duplicate names get a numeric suffix so the unique index can be built
+UPDATE teams
+SET name = teams.name || ' (' || duplicates.position || ')'
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY event_id, lower(name) ORDER BY created_on, id) AS position
+    FROM teams
+) AS duplicates
+WHERE teams.id = duplicates.id AND duplicates.position > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_event_name ON teams (event_id, lower(name));
@@ -0,0 +1,7 @@
+-- weekly availability of a user, windows are in the user's own timezone
+CREATE TABLE IF NOT EXISTS user_availability (
+    user_id UUID references users(id) ON DELETE CASCADE PRIMARY KEY,
+    timezone TEXT NOT NULL,
+    windows JSONB NOT NULL DEFAULT '[]',
+    updated_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
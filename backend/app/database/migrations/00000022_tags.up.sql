@@ -0,0 +1,60 @@
+-- technology tags, slug is the normalized name used for matching
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+-- other spellings that resolve to a tag, stored normalized like slugs
+CREATE TABLE IF NOT EXISTS tag_aliases (
+    alias TEXT PRIMARY KEY,
+    tag_id UUID NOT NULL references tags(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS team_tags (
+    team_id UUID NOT NULL references teams(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL references tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_tags_tag ON team_tags (tag_id);
+
+-- common aliases
+INSERT INTO tags (name, slug) VALUES
+    ('Go', 'go'), ('JavaScript', 'javascript'), ('TypeScript', 'typescript'), ('Python', 'python'),
+    ('PostgreSQL', 'postgresql')
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO tag_aliases (alias, tag_id)
+SELECT aliases.alias, tags.id
+FROM (VALUES ('golang', 'go'), ('js', 'javascript'), ('ts', 'typescript'), ('py', 'python'),
+             ('postgres', 'postgresql')) AS aliases (alias, slug)
+INNER JOIN tags ON (tags.slug = aliases.slug)
+ON CONFLICT (alias) DO NOTHING;
+
+-- tag the existing teams from their comma separated technologies
+WITH technologies AS (
+    SELECT teams.id AS team_id,
+           trim(technology) AS name,
+           regexp_replace(lower(trim(technology)), '\s+', ' ', 'g') AS slug
+    FROM teams, unnest(string_to_array(teams.technologies, ',')) AS technology
+    WHERE trim(technology) <> ''
+), resolved AS (
+    SELECT technologies.team_id, technologies.name, COALESCE(alias_tags.slug, technologies.slug) AS slug
+    FROM technologies
+    LEFT JOIN tag_aliases ON (tag_aliases.alias = technologies.slug)
+    LEFT JOIN tags alias_tags ON (alias_tags.id = tag_aliases.tag_id)
+), created AS (
+    INSERT INTO tags (name, slug)
+    SELECT DISTINCT ON (slug) name, slug FROM resolved
+    ON CONFLICT (slug) DO NOTHING
+    RETURNING id, slug
+)
+INSERT INTO team_tags (team_id, tag_id)
+SELECT DISTINCT resolved.team_id, COALESCE(created.id, tags.id)
+FROM resolved
+LEFT JOIN created ON (created.slug = resolved.slug)
+LEFT JOIN tags ON (tags.slug = resolved.slug)
+WHERE COALESCE(created.id, tags.id) IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_team_size ON team_members;
+DROP FUNCTION IF EXISTS enforce_team_size();
+
+DROP TABLE IF EXISTS team_invitations;
@@ -0,0 +1,49 @@
+-- invitations addressed to a specific user, pending ones past expires_at count as expired
+CREATE TABLE IF NOT EXISTS team_invitations (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    team_id UUID NOT NULL references teams(id) ON DELETE CASCADE,
+    invitee_user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    invited_by_user_id UUID references users(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_on TIMESTAMP WITH TIME ZONE,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+-- a user has at most one pending invitation per team
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_invitation_pending
+    ON team_invitations (team_id, invitee_user_id) WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS idx_team_invitations_invitee ON team_invitations (invitee_user_id, status);
+
+-- the event's team size limit is enforced with a trigger so concurrent joins can't overfill a team
+CREATE OR REPLACE FUNCTION enforce_team_size() RETURNS trigger AS $$
+DECLARE
+    size_limit INTEGER;
+BEGIN
+    SELECT e.max_team_size
+    INTO size_limit
+    FROM teams t
+    INNER JOIN events e ON (e.id = t.event_id)
+    WHERE t.id = NEW.team_id;
+
+    IF size_limit IS NULL OR size_limit <= 0 THEN
+        RETURN NEW;
+    END IF;
+
+    -- serialize joins per team so two concurrent joins can't both take the last slot
+    PERFORM pg_advisory_xact_lock(hashtext('team_size:' || NEW.team_id::text));
+
+    IF (SELECT COUNT(*) FROM team_members WHERE team_id = NEW.team_id AND id <> NEW.id) >= size_limit THEN
+        RAISE EXCEPTION 'team % is full', NEW.team_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'team_size_limit';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_team_size ON team_members;
+CREATE TRIGGER trg_team_size
+    BEFORE INSERT OR UPDATE OF team_id ON team_members
+    FOR EACH ROW EXECUTE FUNCTION enforce_team_size();
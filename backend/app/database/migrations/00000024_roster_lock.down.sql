@@ -0,0 +1,4 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS roster_open_until;
+
+ALTER TABLE events DROP COLUMN IF EXISTS roster_lock_hours;
+ALTER TABLE events DROP COLUMN IF EXISTS roster_lock_policy;
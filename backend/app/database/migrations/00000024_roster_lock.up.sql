@@ -0,0 +1,6 @@
+-- when an event's team rosters lock: AT_START, AFTER_HOURS (roster_lock_hours after starts_at) or NEVER
+ALTER TABLE events ADD COLUMN IF NOT EXISTS roster_lock_policy TEXT NOT NULL DEFAULT 'AT_START';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS roster_lock_hours INTEGER NOT NULL DEFAULT 0;
+
+-- organizers can keep a team's roster open past the event's lock
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS roster_open_until TIMESTAMP WITH TIME ZONE;
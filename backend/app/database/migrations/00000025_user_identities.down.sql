@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_identities;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_user ON users (service_name, service_user_id);
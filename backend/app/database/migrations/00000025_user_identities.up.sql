@@ -0,0 +1,21 @@
+-- a user can log in with several providers.  The service columns on users keep the primary identity for display.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    service_name TEXT NOT NULL,
+    service_user_id TEXT NOT NULL,
+    service_user_name TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT,
+    created_on TIMESTAMP WITH TIME ZONE DEFAULT (now() AT TIME ZONE('utc'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identity ON user_identities (service_name, service_user_id);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id);
+
+INSERT INTO user_identities (user_id, service_name, service_user_id, service_user_name, avatar_url, created_on)
+SELECT id, service_name, service_user_id, COALESCE(service_user_name, ''), avatar_url, created_on
+FROM users
+ON CONFLICT (service_name, service_user_id) DO NOTHING;
+
+-- identities are unique in user_identities now, a user's primary identity can change when it's unlinked
+DROP INDEX IF EXISTS idx_service_user;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_audit_log_event;
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS event_id;
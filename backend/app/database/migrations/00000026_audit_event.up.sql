@@ -0,0 +1,9 @@
+-- audit entries remember the event of their target, so organizers can still read them once a team is disbanded
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS event_id UUID references events(id) ON DELETE CASCADE;
+
+UPDATE audit_log
+SET event_id = teams.event_id
+FROM teams
+WHERE audit_log.target_type = 'TEAM' AND audit_log.target_id = teams.id AND audit_log.event_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log (event_id, created_on);
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type DBNotification struct {
+	Id        pgtype.UUID        `db:"id"`
+	UserId    pgtype.UUID        `db:"user_id" json:"-"`
+	Message   string             `db:"message"`
+	Link      string             `db:"link"`
+	ReadOn    pgtype.Timestamptz `db:"read_on"`
+	CreatedOn pgtype.Timestamptz `db:"created_on"`
+}
+
+func CreateNotification(userId pgtype.UUID, message string, link string) (DBNotification, error) {
+	notification, err := GetRow[DBNotification](
+		`INSERT INTO notifications (user_id, message, link)
+         VALUES ($1, $2, $3)
+         RETURNING *`,
+		userId, message, link)
+	if err != nil {
+		logger.Error("CreateNotification error: %v", err)
+	}
+	return notification, err
+}
+
+// GetNotifications returns the user's notifications, newest first.
+func GetNotifications(userId pgtype.UUID) ([]DBNotification, error) {
+	notifications, err := GetRows[DBNotification](
+		`SELECT * FROM notifications
+         WHERE user_id = $1
+         ORDER BY created_on DESC
+         LIMIT 100`,
+		userId)
+	return notifications, err
+}
+
+// MarkNotificationRead flags the notification as read.  pgx.ErrNoRows is returned if it doesn't belong to the user.
+func MarkNotificationRead(userId pgtype.UUID, notificationId pgtype.UUID) (DBNotification, error) {
+	notification, err := GetRow[DBNotification](
+		`UPDATE notifications
+         SET read_on = COALESCE(read_on, now())
+         WHERE id = $1 AND user_id = $2
+         RETURNING *`,
+		notificationId, userId)
+	return notification, err
+}
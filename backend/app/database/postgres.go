@@ -4,7 +4,9 @@ import (
 	"codejam.io/config"
 	"codejam.io/logging"
 	"context"
+	"errors"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"os"
 )
@@ -37,6 +39,69 @@ func Initialize(config config.DBConfig) {
 	logger.Info("Connected to database")
 }
 
+// IsUniqueViolation reports whether err was caused by the named unique constraint or index.
+func IsUniqueViolation(err error, constraintName string) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505" && pgErr.ConstraintName == constraintName
+	}
+	return false
+}
+
+// IsCheckViolation reports whether err was caused by the named check constraint.
+func IsCheckViolation(err error, constraintName string) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23514" && pgErr.ConstraintName == constraintName
+	}
+	return false
+}
+
+// WithTransaction runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func WithTransaction(fn func(tx pgx.Tx) error) error {
+	tx, err := Pool.Begin(context.Background())
+	if err != nil {
+		logger.Error("begin transaction error %v", err)
+		return err
+	}
+	// a no-op once committed
+	defer tx.Rollback(context.Background())
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
+// Querier runs queries on the Pool or, inside WithTransaction, on the transaction.  Begin starts a savepoint
+// within a transaction, so a statement that may fail can be retried without aborting the whole transaction.
+type Querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// GetRowTx is GetRow for queries that are part of a transaction
+func GetRowTx[T any](tx Querier, query string, args ...any) (T, error) {
+	var result T
+	rows, err := tx.Query(context.Background(), query, args...)
+	if err != nil {
+		logger.Error("Error executing query: error %v, query: %v", err, query)
+		return result, err
+	}
+	return pgx.CollectOneRow(rows, pgx.RowToStructByName[T])
+}
+
+// GetRowsTx is GetRows for queries that are part of a transaction.  Unlike GetRows, errors are returned.
+func GetRowsTx[T any](tx Querier, query string, args ...any) ([]T, error) {
+	rows, err := tx.Query(context.Background(), query, args...)
+	if err != nil {
+		logger.Error("Error executing query: error %v, query: %v", err, query)
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[T])
+}
+
 func GetRow[T any](query string, args ...any) (T, error) {
 	var result T
 	conn, err := Pool.Acquire(context.Background())
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type DBSoloProfile struct {
+	Id           pgtype.UUID        `db:"id"`
+	UserId       pgtype.UUID        `db:"user_id"`
+	EventId      pgtype.UUID        `db:"event_id"`
+	Skills       []string           `db:"skills"`
+	Timezone     string             `db:"timezone"`
+	Availability string             `db:"availability"`
+	Technologies []string           `db:"technologies"`
+	CreatedOn    pgtype.Timestamptz `db:"created_on" json:"-"`
+	UpdatedOn    pgtype.Timestamptz `db:"updated_on"`
+}
+
+// DBSoloProfileInfo includes the display name of the profile's user
+type DBSoloProfileInfo struct {
+	DBSoloProfile
+	DisplayName string `db:"display_name"`
+}
+
+func SaveSoloProfile(profile DBSoloProfile) (DBSoloProfile, error) {
+	profile, err := GetRow[DBSoloProfile](
+		`INSERT INTO solo_profiles (user_id, event_id, skills, timezone, availability, technologies)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         ON CONFLICT (user_id, event_id)
+         DO UPDATE
+         SET skills = $3, timezone = $4, availability = $5, technologies = $6, updated_on = now()
+         RETURNING *`,
+		profile.UserId, profile.EventId, profile.Skills, profile.Timezone, profile.Availability, profile.Technologies)
+	if err != nil {
+		logger.Error("SaveSoloProfile error: %v", err)
+	}
+	return profile, err
+}
+
+func GetSoloProfile(userId pgtype.UUID, eventId pgtype.UUID) (DBSoloProfile, error) {
+	profile, err := GetRow[DBSoloProfile](
+		`SELECT * FROM solo_profiles WHERE user_id = $1 AND event_id = $2`,
+		userId, eventId)
+	return profile, err
+}
+
+func DeleteSoloProfile(userId pgtype.UUID, eventId pgtype.UUID) (DBSoloProfile, error) {
+	profile, err := GetRow[DBSoloProfile](
+		`DELETE FROM solo_profiles
+         WHERE user_id = $1 AND event_id = $2
+         RETURNING *`,
+		userId, eventId)
+	return profile, err
+}
+
+// GetUnteamedSoloProfiles returns the event's solo profiles whose users haven't joined a team in the event yet,
+// oldest first.
+func GetUnteamedSoloProfiles(eventId pgtype.UUID) ([]DBSoloProfileInfo, error) {
+	profiles, err := GetRows[DBSoloProfileInfo](
+		`SELECT sp.*, u.display_name
+         FROM solo_profiles sp
+         INNER JOIN users u ON (u.id = sp.user_id)
+         WHERE sp.event_id = $1
+           AND u.account_status = 'ACTIVE'
+           AND NOT EXISTS (
+             SELECT 1
+             FROM team_members tm
+             INNER JOIN teams t ON (t.id = tm.team_id)
+             WHERE tm.user_id = sp.user_id AND t.event_id = sp.event_id
+           )
+         ORDER BY sp.created_on, sp.id`,
+		eventId)
+	return profiles, err
+}
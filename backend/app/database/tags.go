@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strings"
+)
+
+type DBTag struct {
+	Id        pgtype.UUID        `db:"id"`
+	Name      string             `db:"name"`
+	Slug      string             `db:"slug"`
+	CreatedOn pgtype.Timestamptz `db:"created_on" json:"-"`
+}
+
+// DBTagSuggestion is a tag offered by autocomplete along with how many teams use it
+type DBTagSuggestion struct {
+	DBTag
+	TeamCount int `db:"team_count"`
+}
+
+// DBTagAlias is an alternative spelling along with the slug of the tag it stands for
+type DBTagAlias struct {
+	Alias string `db:"alias"`
+	Slug  string `db:"slug"`
+}
+
+// NormalizeTag turns a tag name into its slug, "  Ruby   on Rails " becomes "ruby on rails".  This matches the
+// normalization in the tags migration.
+func NormalizeTag(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// GetTagBySlugTx finds the tag with the slug, or the tag the slug is an alias of.
+func GetTagBySlugTx(tx Querier, slug string) (DBTag, error) {
+	tag, err := GetRowTx[DBTag](tx,
+		`SELECT tags.*
+         FROM tags
+         WHERE slug = $1 OR id = (SELECT tag_id FROM tag_aliases WHERE alias = $1)
+         LIMIT 1`,
+		slug)
+	return tag, err
+}
+
+// GetTagAliases maps every alias to the slug of its tag.
+func GetTagAliases() (map[string]string, error) {
+	rows, err := GetRows[DBTagAlias](
+		`SELECT ta.alias, tags.slug
+         FROM tag_aliases ta
+         INNER JOIN tags ON (tags.id = ta.tag_id)`)
+	aliases := make(map[string]string, len(rows))
+	for _, row := range rows {
+		aliases[row.Alias] = row.Slug
+	}
+	return aliases, err
+}
+
+// ResolveTagsTx finds the tag for each name, through aliases, creating tags that don't exist yet.  Names that
+// resolve to the same tag are only returned once.  It runs in the transaction saving the team the tags are for, so
+// tags created for a team that fails to save are rolled back with it.
+func ResolveTagsTx(tx Querier, names []string) ([]DBTag, error) {
+	tags := []DBTag{}
+	seen := make(map[pgtype.UUID]bool)
+	for _, name := range names {
+		slug := NormalizeTag(name)
+		if slug == "" {
+			continue
+		}
+
+		tag, err := GetTagBySlugTx(tx, slug)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the no-op update returns the existing row if someone else created the tag in the meantime
+			tag, err = GetRowTx[DBTag](tx,
+				`INSERT INTO tags (name, slug)
+                 VALUES ($1, $2)
+                 ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+                 RETURNING *`,
+				strings.Join(strings.Fields(name), " "), slug)
+		}
+		if err != nil {
+			logger.Error("ResolveTagsTx error: %v", err)
+			return tags, err
+		}
+
+		if !seen[tag.Id] {
+			seen[tag.Id] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// SearchTags returns up to limit tags whose name or an alias starts with the prefix, most used first.
+func SearchTags(prefix string, limit int) ([]DBTagSuggestion, error) {
+	pattern := escapeLike(NormalizeTag(prefix)) + "%"
+	tags, err := GetRows[DBTagSuggestion](
+		`SELECT tags.*,
+                (SELECT COUNT(*)::int FROM team_tags tt WHERE tt.tag_id = tags.id) AS team_count
+         FROM tags
+         WHERE tags.slug LIKE $1
+            OR EXISTS (SELECT 1 FROM tag_aliases ta WHERE ta.tag_id = tags.id AND ta.alias LIKE $1)
+         ORDER BY team_count DESC, tags.slug
+         LIMIT $2`,
+		pattern, limit)
+	return tags, err
+}
+
+func GetTeamTags(teamId pgtype.UUID) ([]DBTag, error) {
+	tags, err := GetRows[DBTag](
+		`SELECT tags.*
+         FROM team_tags tt
+         INNER JOIN tags ON (tags.id = tt.tag_id)
+         WHERE tt.team_id = $1
+         ORDER BY tags.name`,
+		teamId)
+	return tags, err
+}
+
+// SetTeamTagsTx replaces the tags of the team.
+func SetTeamTagsTx(tx Querier, teamId pgtype.UUID, tags []DBTag) error {
+	tagIds := make([]pgtype.UUID, 0, len(tags))
+	for _, tag := range tags {
+		tagIds = append(tagIds, tag.Id)
+	}
+
+	_, err := tx.Exec(context.Background(),
+		`DELETE FROM team_tags WHERE team_id = $1 AND NOT (tag_id = ANY($2))`,
+		teamId, tagIds)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(),
+		`INSERT INTO team_tags (team_id, tag_id)
+         SELECT $1, unnest($2::uuid[])
+         ON CONFLICT DO NOTHING`,
+		teamId, tagIds)
+	return err
+}
+
+// MergeTags folds the source tag into the target.  Teams tagged with the source are tagged with the target instead,
+// and the source's slug and aliases become aliases of the target so old spellings keep resolving.
+func MergeTags(sourceId pgtype.UUID, targetId pgtype.UUID) (DBTag, error) {
+	var target DBTag
+	err := WithTransaction(func(tx pgx.Tx) error {
+		var source DBTag
+		rows, err := tx.Query(context.Background(), `SELECT * FROM tags WHERE id = $1 FOR UPDATE`, sourceId)
+		if err != nil {
+			return err
+		}
+		if source, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[DBTag]); err != nil {
+			return err
+		}
+		rows, err = tx.Query(context.Background(), `SELECT * FROM tags WHERE id = $1 FOR UPDATE`, targetId)
+		if err != nil {
+			return err
+		}
+		if target, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[DBTag]); err != nil {
+			return err
+		}
+
+		statements := []string{
+			`INSERT INTO team_tags (team_id, tag_id)
+             SELECT team_id, $2 FROM team_tags WHERE tag_id = $1
+             ON CONFLICT DO NOTHING`,
+			`UPDATE tag_aliases SET tag_id = $2 WHERE tag_id = $1`,
+			`INSERT INTO tag_aliases (alias, tag_id)
+             SELECT slug, $2 FROM tags WHERE id = $1
+             ON CONFLICT (alias) DO UPDATE SET tag_id = EXCLUDED.tag_id`,
+			`DELETE FROM tags WHERE id = $1`,
+		}
+		for _, statement := range statements {
+			if _, err = tx.Exec(context.Background(), statement, source.Id, target.Id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("MergeTags error: %v", err)
+	}
+	return target, err
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type DBTeamMessage struct {
+	Id        pgtype.UUID        `db:"id"`
+	TeamId    pgtype.UUID        `db:"team_id"`
+	UserId    pgtype.UUID        `db:"user_id"`
+	Body      string             `db:"body"`
+	Pinned    bool               `db:"pinned"`
+	EditedOn  pgtype.Timestamptz `db:"edited_on"`
+	CreatedOn pgtype.Timestamptz `db:"created_on"`
+}
+
+// DBTeamMessageInfo includes the author's display name, which is nil if their account was deleted
+type DBTeamMessageInfo struct {
+	DBTeamMessage
+	DisplayName *string `db:"display_name"`
+	TotalCount  int     `db:"total_count" json:"-"`
+}
+
+func CreateTeamMessage(teamId pgtype.UUID, userId pgtype.UUID, body string) (DBTeamMessage, error) {
+	message, err := GetRow[DBTeamMessage](
+		`INSERT INTO team_messages (team_id, user_id, body)
+         VALUES ($1, $2, $3)
+         RETURNING *`,
+		teamId, userId, body)
+	if err != nil {
+		logger.Error("CreateTeamMessage error: %v", err)
+	}
+	return message, err
+}
+
+func GetTeamMessage(teamId pgtype.UUID, messageId pgtype.UUID) (DBTeamMessage, error) {
+	message, err := GetRow[DBTeamMessage](
+		`SELECT * FROM team_messages WHERE id = $1 AND team_id = $2`,
+		messageId, teamId)
+	return message, err
+}
+
+// GetTeamMessages returns a page of the team's messages, pinned messages first and then newest first, along with
+// the total number of messages.
+func GetTeamMessages(teamId pgtype.UUID, page int, pageSize int) ([]DBTeamMessageInfo, int, error) {
+	messages, err := GetRows[DBTeamMessageInfo](
+		`SELECT m.*, u.display_name, COUNT(*) OVER () AS total_count
+         FROM team_messages m
+         LEFT JOIN users u ON (u.id = m.user_id)
+         WHERE m.team_id = $1
+         ORDER BY m.pinned DESC, m.created_on DESC
+         LIMIT $2 OFFSET $3`,
+		teamId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		logger.Error("GetTeamMessages error: %v", err)
+		return messages, 0, err
+	}
+
+	total := 0
+	if len(messages) > 0 {
+		total = messages[0].TotalCount
+	}
+	return messages, total, nil
+}
+
+func UpdateTeamMessage(teamId pgtype.UUID, messageId pgtype.UUID, body string) (DBTeamMessage, error) {
+	message, err := GetRow[DBTeamMessage](
+		`UPDATE team_messages
+         SET body = $3, edited_on = now()
+         WHERE id = $1 AND team_id = $2
+         RETURNING *`,
+		messageId, teamId, body)
+	return message, err
+}
+
+func SetTeamMessagePinned(teamId pgtype.UUID, messageId pgtype.UUID, pinned bool) (DBTeamMessage, error) {
+	message, err := GetRow[DBTeamMessage](
+		`UPDATE team_messages
+         SET pinned = $3
+         WHERE id = $1 AND team_id = $2
+         RETURNING *`,
+		messageId, teamId, pinned)
+	return message, err
+}
+
+func DeleteTeamMessage(teamId pgtype.UUID, messageId pgtype.UUID) (DBTeamMessage, error) {
+	message, err := GetRow[DBTeamMessage](
+		`DELETE FROM team_messages
+         WHERE id = $1 AND team_id = $2
+         RETURNING *`,
+		messageId, teamId)
+	return message, err
+}
@@ -1,8 +1,13 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"slices"
+	"strings"
 )
 
 type DBTeam struct {
@@ -16,8 +21,32 @@ type DBTeam struct {
 	Description  string           `db:"description"`
 	CreatedOn    pgtype.Timestamp `db:"created_on" json:"createdOn-hidden"`
 	InviteCode   string           `db:"invite_code"`
+	// optional invite limits, nil/invalid means unlimited
+	InviteExpiresAt pgtype.Timestamptz `db:"invite_expires_at"`
+	InviteMaxUses   *int               `db:"invite_max_uses"`
+	InviteUses      int                `db:"invite_uses"`
+	// recruiting info shown in the team search
+	LookingForMembers bool     `db:"looking_for_members"`
+	WantedRoles       []string `db:"wanted_roles"`
+	// locked teams can only be changed by organizers
+	Locked bool `db:"locked"`
+	// organizer override keeping the roster open after the event's roster lock, invalid when not set
+	RosterOpenUntil pgtype.Timestamptz `db:"roster_open_until"`
 }
 
+// Team Roles
+const (
+	TeamRoleOwner   = "owner"
+	TeamRoleCoOwner = "co-owner"
+	TeamRoleMember  = "member"
+)
+
+// Team Visibility
+const (
+	TeamVisibilityPublic  = "public"
+	TeamVisibilityPrivate = "private"
+)
+
 type CreateTeamMember struct {
 	UserId   pgtype.UUID `db:"user_id"`
 	TeamId   pgtype.UUID `db:"team_id"`
@@ -46,17 +75,24 @@ type DBUserTeams struct {
 }
 
 func CreateTeam(team DBTeam) (pgtype.UUID, error) {
-	team, err := GetRow[DBTeam](
+	teamId, err := CreateTeamTx(Pool, team)
+	if err != nil {
+		logger.Error("CreateTeam error: %v", err)
+	}
+	return teamId, err
+}
+
+func CreateTeamTx(tx Querier, team DBTeam) (pgtype.UUID, error) {
+	team, err := GetRowTx[DBTeam](tx,
 		`INSERT INTO teams
-            (event_id, name, visibility, timezone, technologies, availability, description, invite_code)
+            (event_id, name, visibility, timezone, technologies, availability, description, invite_code,
+             looking_for_members, wanted_roles)
             VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8)
-        RETURNING id, event_id, name, visibility, timezone, technologies, availability, description, created_on, invite_code
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING *
 		`,
-		team.EventId, team.Name, team.Visibility, team.Timezone, team.Technologies, team.Availability, team.Description, team.InviteCode)
-	if err != nil {
-		fmt.Println("ERROR: failed to create team: ", err)
-	}
+		team.EventId, team.Name, team.Visibility, team.Timezone, team.Technologies, team.Availability, team.Description, team.InviteCode,
+		team.LookingForMembers, team.WantedRoles)
 	return team.Id, err
 }
 
@@ -73,7 +109,14 @@ func GetTeam(teamId pgtype.UUID) (DBTeam, error) {
 			teams.availability,
 			teams.description,
 			teams.created_on,
-			teams.invite_code
+			teams.invite_code,
+			teams.invite_expires_at,
+			teams.invite_max_uses,
+			teams.invite_uses,
+			teams.looking_for_members,
+			teams.wanted_roles,
+			teams.locked,
+			teams.roster_open_until
 		FROM teams
 		WHERE teams.id = $1`,
 		teamId)
@@ -98,9 +141,18 @@ func GetTeamByInvite(inviteCode string) (DBTeam, error) {
 			teams.availability,
 			teams.description,
 			teams.created_on,
-			teams.invite_code
+			teams.invite_code,
+			teams.invite_expires_at,
+			teams.invite_max_uses,
+			teams.invite_uses,
+			teams.looking_for_members,
+			teams.wanted_roles,
+			teams.locked,
+			teams.roster_open_until
 		FROM teams
-		WHERE teams.invite_code = $1`,
+		WHERE teams.invite_code = $1
+		  AND (teams.invite_expires_at IS NULL OR teams.invite_expires_at > now())
+		  AND (teams.invite_max_uses IS NULL OR teams.invite_uses < teams.invite_max_uses)`,
 		inviteCode)
 	if err != nil {
 		logger.Error("===DB/GetTeamByInvite error: ", err)
@@ -109,9 +161,172 @@ func GetTeamByInvite(inviteCode string) (DBTeam, error) {
 	return team, nil
 }
 
-func GetTeams() ([]DBTeam, error) {
-	result, err := GetRows[DBTeam](`SELECT * FROM teams`)
-	return result, err
+// ClaimTeamInviteTx counts a use of the invite code, provided it hasn't expired or run out of uses.
+// pgx.ErrNoRows is returned if the invite code can't be used.
+func ClaimTeamInviteTx(tx Querier, teamId pgtype.UUID, inviteCode string) (DBTeam, error) {
+	team, err := GetRowTx[DBTeam](tx,
+		`UPDATE teams
+         SET invite_uses = invite_uses + 1
+         WHERE id = $1
+           AND invite_code = $2
+           AND (invite_expires_at IS NULL OR invite_expires_at > now())
+           AND (invite_max_uses IS NULL OR invite_uses < invite_max_uses)
+         RETURNING *`,
+		teamId, inviteCode)
+	return team, err
+}
+
+// JoinTeamWithInvite claims a use of the invite code and adds the user to the team together, so a join that fails
+// doesn't use up the code.  pgx.ErrNoRows is returned if the invite code can't be used, AddTeamMember's errors if the
+// user can't join.
+func JoinTeamWithInvite(teamId pgtype.UUID, inviteCode string, userId pgtype.UUID) (DBTeam, error) {
+	var team DBTeam
+	err := WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if team, err = ClaimTeamInviteTx(tx, teamId, inviteCode); err != nil {
+			return err
+		}
+		_, err = AddTeamMemberTx(tx, userId, teamId, TeamRoleMember)
+		return err
+	})
+	return team, err
+}
+
+// SetTeamInviteCode replaces the team's invite code, resetting the use count and applying the new limits.
+func SetTeamInviteCode(teamId pgtype.UUID, inviteCode string, expiresAt pgtype.Timestamptz, maxUses *int) (DBTeam, error) {
+	team, err := GetRow[DBTeam](
+		`UPDATE teams
+         SET invite_code = $2,
+             invite_expires_at = $3,
+             invite_max_uses = $4,
+             invite_uses = 0
+         WHERE id = $1
+         RETURNING *`,
+		teamId, inviteCode, expiresAt, maxUses)
+	return team, err
+}
+
+// TeamSearch holds the optional filters for SearchTeams.  Nil/empty filters are not applied.
+type TeamSearch struct {
+	EventId           pgtype.UUID
+	Query             string
+	Technologies      []string
+	Availability      string
+	WantedRole        string
+	LookingForMembers *bool
+	MinOpenSlots      int
+	// UTC offset range in hours, teams without a recognized timezone are excluded when either is set
+	MinUTCOffset *float64
+	MaxUTCOffset *float64
+	Page         int
+	PageSize     int
+}
+
+// DBTeamListing is a team in search results along with how full it is
+type DBTeamListing struct {
+	DBTeam
+	MemberCount int      `db:"member_count"`
+	OpenSlots   int      `db:"open_slots"` // -1 when the event has no team size limit
+	Tags        []string `db:"tags"`
+	TotalCount  int      `db:"total_count" json:"-"`
+}
+
+// escapeLike escapes LIKE wildcards so user input only matches literally
+func escapeLike(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "%", `\%`)
+	return strings.ReplaceAll(value, "_", `\_`)
+}
+
+// likePattern matches values containing the user input
+func likePattern(value string) string {
+	return "%" + escapeLike(value) + "%"
+}
+
+// SearchTeams returns a page of public teams in the event matching the search, along with the total match count.
+func SearchTeams(search TeamSearch) ([]DBTeamListing, int, error) {
+	var conditions []string
+	args := []any{search.EventId, TeamVisibilityPublic}
+	addArg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if search.Query != "" {
+		param := addArg(likePattern(search.Query))
+		conditions = append(conditions, fmt.Sprintf("(teams.name ILIKE %s OR teams.description ILIKE %s)", param, param))
+	}
+	for _, technology := range search.Technologies {
+		// matched against the team's tags, so aliases like "golang" find teams tagged "Go"
+		param := addArg(NormalizeTag(technology))
+		conditions = append(conditions, fmt.Sprintf(
+			`EXISTS (
+               SELECT 1 FROM team_tags tt INNER JOIN tags ON (tags.id = tt.tag_id)
+               WHERE tt.team_id = teams.id
+                 AND (tags.slug = %s OR tags.id = (SELECT tag_id FROM tag_aliases WHERE alias = %s))
+             )`, param, param))
+	}
+	if search.Availability != "" {
+		conditions = append(conditions, "teams.availability ILIKE "+addArg(likePattern(search.Availability)))
+	}
+	if search.WantedRole != "" {
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM unnest(teams.wanted_roles) role WHERE lower(role) = lower("+addArg(search.WantedRole)+"))")
+	}
+	if search.LookingForMembers != nil {
+		conditions = append(conditions, "teams.looking_for_members = "+addArg(*search.LookingForMembers))
+	}
+	if search.MinOpenSlots > 0 {
+		conditions = append(conditions,
+			fmt.Sprintf("(e.max_team_size <= 0 OR e.max_team_size - mc.member_count >= %s)", addArg(search.MinOpenSlots)))
+	}
+	if search.MinUTCOffset != nil {
+		conditions = append(conditions, "EXTRACT(EPOCH FROM tz.utc_offset) / 3600 >= "+addArg(*search.MinUTCOffset))
+	}
+	if search.MaxUTCOffset != nil {
+		conditions = append(conditions, "EXTRACT(EPOCH FROM tz.utc_offset) / 3600 <= "+addArg(*search.MaxUTCOffset))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " AND " + strings.Join(conditions, " AND ")
+	}
+
+	limit := addArg(search.PageSize)
+	offset := addArg((search.Page - 1) * search.PageSize)
+
+	listings, err := GetRows[DBTeamListing](
+		`SELECT teams.*,
+                mc.member_count,
+                CASE WHEN e.max_team_size <= 0 THEN -1
+                     ELSE GREATEST(e.max_team_size - mc.member_count, 0)
+                END AS open_slots,
+                ARRAY(
+                  SELECT tags.name FROM team_tags tt INNER JOIN tags ON (tags.id = tt.tag_id)
+                  WHERE tt.team_id = teams.id
+                  ORDER BY tags.name
+                ) AS tags,
+                COUNT(*) OVER () AS total_count
+         FROM teams
+         INNER JOIN events e ON (e.id = teams.event_id)
+         LEFT JOIN pg_timezone_names tz ON (tz.name = teams.timezone)
+         CROSS JOIN LATERAL (
+           SELECT COUNT(*)::int AS member_count FROM team_members tm WHERE tm.team_id = teams.id
+         ) mc
+         WHERE teams.event_id = $1 AND teams.visibility = $2`+where+`
+         ORDER BY teams.looking_for_members DESC, teams.created_on
+         LIMIT `+limit+` OFFSET `+offset,
+		args...)
+	if err != nil {
+		logger.Error("SearchTeams error: %v", err)
+		return listings, 0, err
+	}
+
+	total := 0
+	if len(listings) > 0 {
+		total = listings[0].TotalCount
+	}
+	return listings, total, nil
 }
 
 func GetUserTeams(userId pgtype.UUID) ([]DBUserTeams, error) {
@@ -126,34 +341,50 @@ func GetUserTeams(userId pgtype.UUID) ([]DBUserTeams, error) {
 		WHERE u.id = $1`,
 		userId)
 	if err != nil {
-		fmt.Println("that didn't work: database.GetUserTeams")
+		logger.Error("GetUserTeams error: %v", err)
 		return nil, err
 	}
 
 	return result, err  // Try look at the table
 }
 
+// UpdateTeam saves the user editable fields of a team.  EventId and InviteCode are intentionally not updated.
 func UpdateTeam(team DBTeam) (DBTeam, error) {
-	event, err := GetRow[DBTeam](
+	team, err := UpdateTeamTx(Pool, team)
+	if err != nil {
+		logger.Error("UpdateTeam error: %v", err)
+	}
+	return team, err
+}
+
+func UpdateTeamTx(tx Querier, team DBTeam) (DBTeam, error) {
+	team, err := GetRowTx[DBTeam](tx,
 		`UPDATE teams
-            SET name=$2,
-                visibility=$3,
-				timezone=$4,
-				technologies=$5,
-				availability=$6,
-				description=$7,
-		WHERE id=$1
-		RETURNING *`,
-		team.Id, team.Name, team.Visibility, team.Timezone, team.Technologies, team.Availability, team.Description)
-	return event, err
+         SET name=$2,
+             visibility=$3,
+             timezone=$4,
+             technologies=$5,
+             availability=$6,
+             description=$7,
+             looking_for_members=$8,
+             wanted_roles=$9
+         WHERE id=$1
+         RETURNING *`,
+		team.Id, team.Name, team.Visibility, team.Timezone, team.Technologies, team.Availability, team.Description,
+		team.LookingForMembers, team.WantedRoles)
+	return team, err
 }
 
 // fields: userid, teamid, role
 // called at server/teams.go createTeam & when someone clicks "join team"
 // DONT MESS WITH BELOW. IT WORKS.
+// Fails with a OneTeamPerEventConstraint unique violation if the user already has a team in the event.
 func AddTeamMember(userId pgtype.UUID, teamUUID pgtype.UUID, role string) (userID pgtype.UUID, err error) {
-	fmt.Println("=== line 100 userId", userId)
-	teamMember, err := GetRow[CreateTeamMember](
+	return AddTeamMemberTx(Pool, userId, teamUUID, role)
+}
+
+func AddTeamMemberTx(tx Querier, userId pgtype.UUID, teamUUID pgtype.UUID, role string) (pgtype.UUID, error) {
+	teamMember, err := GetRowTx[CreateTeamMember](tx,
 		`INSERT INTO team_members
 			(user_id, team_id, team_role)
 			VALUES ($1, $2, $3)
@@ -161,6 +392,321 @@ func AddTeamMember(userId pgtype.UUID, teamUUID pgtype.UUID, role string) (userI
 	return teamMember.UserId, err
 }
 
+// OneTeamPerEventConstraint is reported by AddTeamMember when the user is already on a team in the event
+const OneTeamPerEventConstraint = "one_team_per_event"
+
+// TeamSizeConstraint is reported by AddTeamMember as a check violation when the team is at the event's size limit
+const TeamSizeConstraint = "team_size_limit"
+
+// TeamNameConstraint is reported by CreateTeam and UpdateTeam when the event already has a team with the name
+const TeamNameConstraint = "idx_teams_event_name"
+
+type DBTeamNameTaken struct {
+	Taken bool `db:"taken"`
+}
+
+type DBTeamRoom struct {
+	HasRoom bool `db:"has_room"`
+}
+
+// TeamHasRoom reports whether the team is below its event's team size limit.
+func TeamHasRoom(teamId pgtype.UUID) (bool, error) {
+	result, err := GetRow[DBTeamRoom](
+		`SELECT (e.max_team_size <= 0
+                 OR (SELECT COUNT(*) FROM team_members tm WHERE tm.team_id = t.id) < e.max_team_size) AS has_room
+         FROM teams t
+         INNER JOIN events e ON (e.id = t.event_id)
+         WHERE t.id = $1`,
+		teamId)
+	return result.HasRoom, err
+}
+
+type DBJoinEventCheck struct {
+	Allowed bool `db:"allowed"`
+}
+
+// CanJoinEventTeam reports whether the user may join another team in the event.  That's only when they aren't on
+// one yet, unless the event allows multiple teams.
+func CanJoinEventTeam(userId pgtype.UUID, eventId pgtype.UUID) (bool, error) {
+	result, err := GetRow[DBJoinEventCheck](
+		`SELECT e.allow_multiple_teams OR NOT EXISTS (
+           SELECT 1
+           FROM team_members tm
+           INNER JOIN teams t ON (t.id = tm.team_id)
+           WHERE tm.user_id = $1 AND t.event_id = e.id
+         ) AS allowed
+         FROM events e
+         WHERE e.id = $2`,
+		userId, eventId)
+	return result.Allowed, err
+}
+
+type DBTeamMembership struct {
+	IsMember bool `db:"is_member"`
+}
+
+// IsTeamMember reports whether the user already belongs to the given team.
+func IsTeamMember(teamId pgtype.UUID, userId pgtype.UUID) (bool, error) {
+	result, err := GetRow[DBTeamMembership](
+		`SELECT EXISTS (
+           SELECT 1 FROM team_members WHERE team_id = $1 AND user_id = $2
+         ) AS is_member`,
+		teamId, userId)
+	return result.IsMember, err
+}
+
+// TeamNameTaken reports whether another team in the event already uses the name, ignoring case.  teamId is the team
+// being renamed, or an invalid UUID for a new team.
+func TeamNameTaken(eventId pgtype.UUID, name string, teamId pgtype.UUID) (bool, error) {
+	result, err := GetRow[DBTeamNameTaken](
+		`SELECT EXISTS (
+           SELECT 1 FROM teams WHERE event_id = $1 AND lower(name) = lower($2) AND id IS DISTINCT FROM $3
+         ) AS taken`,
+		eventId, name, teamId)
+	return result.Taken, err
+}
+
+func GetTeamMember(teamId pgtype.UUID, userId pgtype.UUID) (DBTeamMember, error) {
+	member, err := GetRow[DBTeamMember](
+		`SELECT * FROM team_members WHERE team_id = $1 AND user_id = $2`,
+		teamId, userId)
+	return member, err
+}
+
+// ErrTeamRoleProtected is returned by RemoveTeamMember when the member's role can't be removed
+var ErrTeamRoleProtected = errors.New("the member's role can't be removed")
+
+// ErrOwnershipNotTransferred is returned by TransferTeamOwnership when the owner or the new owner changed before
+// the transfer
+var ErrOwnershipNotTransferred = errors.New("ownership was not transferred")
+
+// RemoveTeamMember deletes the user's membership with the team row locked, as long as their role is one of
+// removableRoles.  pgx.ErrNoRows is returned if they weren't a member, ErrTeamRoleProtected if their role can't be
+// removed.
+func RemoveTeamMember(teamId pgtype.UUID, userId pgtype.UUID, removableRoles []string) (DBTeamMember, error) {
+	var member DBTeamMember
+	err := WithTransaction(func(tx pgx.Tx) error {
+		if err := LockTeamTx(tx, teamId); err != nil {
+			return err
+		}
+		current, err := GetRowTx[DBTeamMember](tx,
+			`SELECT * FROM team_members WHERE team_id = $1 AND user_id = $2`,
+			teamId, userId)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(removableRoles, current.TeamRole) {
+			return ErrTeamRoleProtected
+		}
+		member, err = GetRowTx[DBTeamMember](tx,
+			`DELETE FROM team_members
+             WHERE team_id = $1 AND user_id = $2 AND team_role = ANY($3)
+             RETURNING *`,
+			teamId, userId, removableRoles)
+		return err
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, ErrTeamRoleProtected) {
+		logger.Error("RemoveTeamMember error: %v", err)
+	}
+	return member, err
+}
+
+// TransferTeamOwnership makes newOwnerId the team owner and demotes the current owner to a member with the team row
+// locked, so the team is never without (or with two) owners.  pgx.ErrNoRows is returned if newOwnerId isn't on the
+// team, ErrOwnershipNotTransferred if ownerId no longer owns it.
+func TransferTeamOwnership(teamId pgtype.UUID, ownerId pgtype.UUID, newOwnerId pgtype.UUID) ([]DBTeamMember, error) {
+	var members []DBTeamMember
+	err := WithTransaction(func(tx pgx.Tx) error {
+		if err := LockTeamTx(tx, teamId); err != nil {
+			return err
+		}
+		if _, err := GetRowTx[DBTeamMember](tx,
+			`SELECT * FROM team_members WHERE team_id = $1 AND user_id = $2`,
+			teamId, newOwnerId); err != nil {
+			return err
+		}
+
+		var err error
+		members, err = GetRowsTx[DBTeamMember](tx,
+			`UPDATE team_members
+             SET team_role = CASE WHEN user_id = $3 THEN $4 ELSE $5 END
+             WHERE team_id = $1 AND ((user_id = $2 AND team_role = $4) OR user_id = $3)
+             RETURNING *`,
+			teamId, ownerId, newOwnerId, TeamRoleOwner, TeamRoleMember)
+		if err == nil && len(members) != 2 {
+			err = ErrOwnershipNotTransferred
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, ErrOwnershipNotTransferred) {
+		logger.Error("TransferTeamOwnership error: %v", err)
+	}
+	return members, err
+}
+
+// SetTeamMemberRole changes a member's role.  pgx.ErrNoRows is returned if they aren't on the team.
+func SetTeamMemberRole(teamId pgtype.UUID, userId pgtype.UUID, teamRole string) (DBTeamMember, error) {
+	member, err := GetRow[DBTeamMember](
+		`UPDATE team_members
+         SET team_role = $3
+         WHERE team_id = $1 AND user_id = $2
+         RETURNING *`,
+		teamId, userId, teamRole)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("SetTeamMemberRole error: %v", err)
+	}
+	return member, err
+}
+
+// SetTeamRosterOpenUntilTx sets or, with an invalid time, clears the team's roster lock override.
+func SetTeamRosterOpenUntilTx(tx Querier, teamId pgtype.UUID, openUntil pgtype.Timestamptz) (DBTeam, error) {
+	team, err := GetRowTx[DBTeam](tx,
+		`UPDATE teams
+         SET roster_open_until = $2
+         WHERE id = $1
+         RETURNING *`,
+		teamId, openUntil)
+	return team, err
+}
+
+func SetTeamLockedTx(tx Querier, teamId pgtype.UUID, locked bool) (DBTeam, error) {
+	team, err := GetRowTx[DBTeam](tx,
+		`UPDATE teams
+         SET locked = $2
+         WHERE id = $1
+         RETURNING *`,
+		teamId, locked)
+	return team, err
+}
+
+// DeleteTeamTx removes the team along with its members and join requests.
+func DeleteTeamTx(tx Querier, teamId pgtype.UUID) (DBTeam, error) {
+	team, err := GetRowTx[DBTeam](tx,
+		`DELETE FROM teams
+         WHERE id = $1
+         RETURNING *`,
+		teamId)
+	return team, err
+}
+
+// PromoteOldestMemberTx makes the longest standing member the owner when the team has no owner left, co-owners
+// are picked before members.
+// pgx.ErrNoRows is returned when nobody was promoted.
+func PromoteOldestMemberTx(tx Querier, teamId pgtype.UUID) (DBTeamMember, error) {
+	member, err := GetRowTx[DBTeamMember](tx,
+		`UPDATE team_members
+         SET team_role = $2
+         WHERE id = (
+           SELECT id FROM team_members
+           WHERE team_id = $1
+           ORDER BY team_role = $3 DESC, created_on, id
+           LIMIT 1
+         )
+           AND NOT EXISTS (SELECT 1 FROM team_members WHERE team_id = $1 AND team_role = $2)
+         RETURNING *`,
+		teamId, TeamRoleOwner, TeamRoleCoOwner)
+	return member, err
+}
+
+// DisbandTeamIfEmptyTx deletes the team when it no longer has any members.  Returns true if the team was deleted.
+func DisbandTeamIfEmptyTx(tx Querier, teamId pgtype.UUID) (bool, error) {
+	_, err := GetRowTx[DBTeam](tx,
+		`DELETE FROM teams
+         WHERE id = $1
+           AND NOT EXISTS (SELECT 1 FROM team_members WHERE team_id = $1)
+         RETURNING *`,
+		teamId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// LockTeamTx locks the team row until the transaction ends, so membership changes to the team happen one at a time.
+// pgx.ErrNoRows is returned if the team doesn't exist.
+func LockTeamTx(tx Querier, teamId pgtype.UUID) error {
+	tag, err := tx.Exec(context.Background(), `SELECT 1 FROM teams WHERE id = $1 FOR UPDATE`, teamId)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = pgx.ErrNoRows
+	}
+	return err
+}
+
+// ErrOwnerMustTransfer is returned by LeaveTeam when the owner leaves a team that still has other members
+var ErrOwnerMustTransfer = errors.New("the owner must transfer ownership before leaving")
+
+// DBTeamLeave is the outcome of a member leaving or being removed from a team
+type DBTeamLeave struct {
+	Member    DBTeamMember
+	Disbanded bool
+}
+
+type DBTeamMemberCount struct {
+	MemberCount int `db:"member_count"`
+}
+
+// LeaveTeam removes the user from the team with the team row locked.  A new owner is promoted if the owner was
+// removed and a team left without members is disbanded, so members leaving together can't leave the team without an
+// owner.  With ownerMustBeLast the owner can only leave as the last member, otherwise ErrOwnerMustTransfer is
+// returned.  pgx.ErrNoRows is returned if the user isn't on the team.
+func LeaveTeam(teamId pgtype.UUID, userId pgtype.UUID, ownerMustBeLast bool) (DBTeamLeave, error) {
+	var result DBTeamLeave
+	err := WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		result, err = LeaveTeamTx(tx, teamId, userId, ownerMustBeLast)
+		return err
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, ErrOwnerMustTransfer) {
+		logger.Error("LeaveTeam error: %v", err)
+	}
+	return result, err
+}
+
+func LeaveTeamTx(tx Querier, teamId pgtype.UUID, userId pgtype.UUID, ownerMustBeLast bool) (DBTeamLeave, error) {
+	var result DBTeamLeave
+	if err := LockTeamTx(tx, teamId); err != nil {
+		return result, err
+	}
+
+	if ownerMustBeLast {
+		member, err := GetRowTx[DBTeamMember](tx,
+			`SELECT * FROM team_members WHERE team_id = $1 AND user_id = $2`,
+			teamId, userId)
+		if err != nil {
+			return result, err
+		}
+		if member.TeamRole == TeamRoleOwner {
+			others, err := GetRowTx[DBTeamMemberCount](tx,
+				`SELECT COUNT(*)::int AS member_count FROM team_members WHERE team_id = $1 AND user_id <> $2`,
+				teamId, userId)
+			if err != nil {
+				return result, err
+			}
+			if others.MemberCount > 0 {
+				return result, ErrOwnerMustTransfer
+			}
+		}
+	}
+
+	member, err := GetRowTx[DBTeamMember](tx,
+		`DELETE FROM team_members
+         WHERE team_id = $1 AND user_id = $2
+         RETURNING *`,
+		teamId, userId)
+	if err != nil {
+		return result, err
+	}
+	result.Member = member
+
+	if member.TeamRole == TeamRoleOwner {
+		if _, err = PromoteOldestMemberTx(tx, teamId); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return result, err
+		}
+	}
+	result.Disbanded, err = DisbandTeamIfEmptyTx(tx, teamId)
+	return result, err
+}
+
 func GetMembersByTeamId(teamId pgtype.UUID) (*[]DBTeamMemberInfo, error) {
 	// In Go, you never return slice-data.
 	// Having * in sig means I'm returning the slice-header, which means I need & in my return
@@ -23,19 +23,18 @@ const (
 	Admin = "ADMIN"
 )
 
-func CreateUser(serviceName string, serviceUserId string, serviceDisplayName string, avatarUrl string) DBUser {
-	user, err := GetRow[DBUser](
-		`INSERT INTO users (service_name, service_user_id, service_user_name, display_name, avatar_url)
-		 VALUES ($1, $2, $3, $3, $4)
-		 ON CONFLICT (service_name, service_user_id)
-		 DO UPDATE
-		 SET service_user_name = $3, avatar_url = $4
-		 RETURNING *`,
-		serviceName, serviceUserId, serviceDisplayName, avatarUrl)
-	if err != nil {
-		logger.Error("error getting user: %v", err)
-	}
-	return user
+// Account Statuses
+const (
+	AccountActive = "ACTIVE"
+	AccountBanned = "BANNED"
+)
+
+// GetUsersByDisplayName returns the users with the display name, ignoring case.  Display names aren't unique.
+func GetUsersByDisplayName(displayName string) ([]DBUser, error) {
+	users, err := GetRows[DBUser](
+		`SELECT * FROM users WHERE lower(display_name) = lower($1) ORDER BY created_on`,
+		displayName)
+	return users, err
 }
 
 func GetUser(userId pgtype.UUID) (DBUser, error) {
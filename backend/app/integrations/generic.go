@@ -3,6 +3,7 @@ package integrations
 import (
 	"codejam.io/integrations/discord"
 	"codejam.io/integrations/github"
+	"codejam.io/integrations/oidc"
 	"codejam.io/logging"
 	"encoding/json"
 	"strings"
@@ -48,6 +49,32 @@ func getDiscordUser(accessToken string) *IntegrationUser {
 	}
 }
 
+// GetOIDCUser verifies the ID token from an OpenID Connect login and maps its claims to the user.  Providers don't all
+// send a username, so the name claim is used when the configured one is missing.
+func GetOIDCUser(provider *oidc.Provider, idToken string, nonce string) *IntegrationUser {
+	claims, err := provider.VerifyIDToken(idToken, nonce)
+	if err != nil {
+		logger.Error("Invalid ID token from %s: %v", provider.Issuer, err)
+		return nil
+	}
+
+	userId := claims.String(provider.Claims.UserId)
+	if userId == "" {
+		logger.Error("ID token from %s has no %s claim", provider.Issuer, provider.Claims.UserId)
+		return nil
+	}
+	userName := claims.String(provider.Claims.UserName)
+	if userName == "" {
+		userName = claims.String("name")
+	}
+	return &IntegrationUser{
+		IntegrationName: "oidc",
+		UserId:          userId,
+		ServiceUserName: userName,
+		AvatarUrl:       claims.String(provider.Claims.Avatar),
+	}
+}
+
 func GetUser(integrationName string, accessToken string) *IntegrationUser {
 	switch strings.ToLower(integrationName) {
 	case "github":
@@ -0,0 +1,228 @@
+package oidc
+
+import (
+	"codejam.io/logging"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logging.NewLogger(logging.Options{Name: "OIDC", Level: logging.INFO})
+
+// keyRefreshInterval limits how often the JWKS is fetched again for tokens signed with an unknown key, so a flood of
+// bad tokens can't hammer the issuer
+const keyRefreshInterval = time.Minute
+
+// Discovery is the part of the issuer's openid-configuration document that logging in needs
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// ClaimMapping names the ID token claims IntegrationUser fields are read from
+type ClaimMapping struct {
+	UserId   string
+	UserName string
+	Avatar   string
+}
+
+// Provider is an OpenID Connect issuer, such as Keycloak or GitLab, that ID tokens for ClientId are verified against.
+type Provider struct {
+	Discovery
+	ClientId string
+	Claims   ClaimMapping
+
+	client      *http.Client
+	mutex       sync.Mutex
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// Discover loads the issuer's openid-configuration and signing keys.  The issuer URL must match the one in the
+// document exactly, as it's compared against the iss claim of every token.
+func Discover(issuer string, clientId string, claims ClaimMapping) (*Provider, error) {
+	if claims.UserId == "" {
+		claims.UserId = "sub"
+	}
+	if claims.UserName == "" {
+		claims.UserName = "preferred_username"
+	}
+	if claims.Avatar == "" {
+		claims.Avatar = "picture"
+	}
+	provider := &Provider{
+		ClientId: clientId,
+		Claims:   claims,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := provider.getJSON(url, &provider.Discovery); err != nil {
+		return nil, err
+	}
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("issuer %s doesn't match the configured %s", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JwksUri == "" {
+		return nil, fmt.Errorf("openid-configuration of %s is missing endpoints", issuer)
+	}
+
+	if err := provider.refreshKeys(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// NewNonce returns a random value to bind an ID token to the login that asked for it
+func NewNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+func (provider *Provider) getJSON(url string, target any) error {
+	resp, err := provider.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+func (key jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point isn't on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", key.Kty)
+	}
+}
+
+// refreshKeys replaces the signing keys with the issuer's current JWKS.  Keys that can't be used are skipped.
+func (provider *Provider) refreshKeys() error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := provider.getJSON(provider.JwksUri, &jwks); err != nil {
+		return err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logger.Warn("Skipping key %s of %s: %v", jwk.Kid, provider.Issuer, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no usable signing keys at %s", provider.JwksUri)
+	}
+
+	provider.keys = keys
+	provider.keysFetched = time.Now()
+	return nil
+}
+
+// key finds the signing key by id, fetching the JWKS again when the issuer has rotated its keys.  Tokens without a
+// key id can only be verified when the issuer has a single key.
+func (provider *Provider) key(kid string) (crypto.PublicKey, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	find := func() crypto.PublicKey {
+		if kid == "" && len(provider.keys) == 1 {
+			for _, key := range provider.keys {
+				return key
+			}
+		}
+		return provider.keys[kid]
+	}
+
+	if key := find(); key != nil {
+		return key, nil
+	}
+	if time.Since(provider.keysFetched) >= keyRefreshInterval {
+		if err := provider.refreshKeys(); err != nil {
+			return nil, err
+		}
+		if key := find(); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testClientId = "codejam"
+
+// testIssuer is an OpenID Connect issuer serving discovery and a JWKS, which mints ID tokens with its keys
+type testIssuer struct {
+	server *httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mutex sync.Mutex
+	// issuer is the issuer reported by discovery, the server's URL unless a test changes it
+	issuer       string
+	keys         []jsonWebKey
+	jwksRequests int
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Use: "sig",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "EC",
+		Use: "sig",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{
+		rsaKey: rsaKey,
+		ecKey:  ecKey,
+		keys:   []jsonWebKey{rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey)},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+		_ = json.NewEncoder(w).Encode(Discovery{
+			Issuer:                issuer.issuer,
+			AuthorizationEndpoint: issuer.server.URL + "/authorize",
+			TokenEndpoint:         issuer.server.URL + "/token",
+			JwksUri:               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+		issuer.jwksRequests++
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": issuer.keys})
+	})
+	issuer.server = httptest.NewServer(mux)
+	issuer.issuer = issuer.server.URL
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// discover runs discovery against the issuer, failing the test if it doesn't succeed
+func (issuer *testIssuer) discover(t *testing.T) *Provider {
+	t.Helper()
+	provider, err := Discover(issuer.server.URL, testClientId, ClaimMapping{})
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	return provider
+}
+
+func (issuer *testIssuer) setKeys(keys ...jsonWebKey) {
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	issuer.keys = keys
+}
+
+func (issuer *testIssuer) jwksRequestCount() int {
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	return issuer.jwksRequests
+}
+
+// claims returns the claims of a valid token for the test client
+func (issuer *testIssuer) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":                issuer.server.URL,
+		"sub":                "user-1",
+		"aud":                testClientId,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              "nonce-1",
+		"preferred_username": "alice",
+	}
+}
+
+// mint signs the claims with the algorithm.  RS256 uses the RSA key and ES256 the EC key, HS256 is keyed with the
+// RSA public key to try an algorithm confusion, none isn't signed at all.
+func (issuer *testIssuer) mint(t *testing.T, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, issuer.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, issuer.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case "HS256":
+		mac := hmac.New(sha256.New, issuer.rsaKey.PublicKey.N.Bytes())
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "none":
+	default:
+		t.Fatalf("can't mint %s tokens", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestDiscover(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.discover(t)
+
+	if provider.Issuer != issuer.server.URL || provider.JwksUri != issuer.server.URL+"/jwks" {
+		t.Errorf("Discovery = %+v", provider.Discovery)
+	}
+	if len(provider.keys) != 2 {
+		t.Errorf("got %d keys, want 2", len(provider.keys))
+	}
+	if provider.Claims != (ClaimMapping{UserId: "sub", UserName: "preferred_username", Avatar: "picture"}) {
+		t.Errorf("Claims = %+v, want the defaults", provider.Claims)
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	issuer.mutex.Lock()
+	issuer.issuer = "https://evil.example"
+	issuer.mutex.Unlock()
+	if _, err := Discover(issuer.server.URL, testClientId, ClaimMapping{}); err == nil {
+		t.Error("Discover accepted a document for another issuer")
+	}
+
+	// the issuer is compared exactly, a trailing slash makes it a different issuer
+	issuer.mutex.Lock()
+	issuer.issuer = issuer.server.URL
+	issuer.mutex.Unlock()
+	if _, err := Discover(issuer.server.URL+"/", testClientId, ClaimMapping{}); err == nil {
+		t.Error("Discover accepted an issuer differing by a trailing slash")
+	}
+}
+
+func TestDiscoverSkipsUnusableKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	encryption := rsaJWK("enc", &issuer.rsaKey.PublicKey)
+	encryption.Use = "enc"
+	offCurve := ecJWK("off-curve", &issuer.ecKey.PublicKey)
+	offCurve.Y = offCurve.X
+	issuer.setKeys(encryption, offCurve, jsonWebKey{Kty: "oct", Kid: "secret"})
+
+	if _, err := Discover(issuer.server.URL, testClientId, ClaimMapping{}); err == nil {
+		t.Error("Discover accepted a JWKS without usable signing keys")
+	}
+
+	issuer.setKeys(encryption, offCurve, rsaJWK("rsa", &issuer.rsaKey.PublicKey))
+	provider := issuer.discover(t)
+	if _, ok := provider.keys["rsa"]; !ok || len(provider.keys) != 1 {
+		t.Errorf("keys = %v, want only rsa", provider.keys)
+	}
+}
+
+func TestUnknownKeyRefreshesKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.discover(t)
+
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.setKeys(rsaJWK("rotated", &rotated.PublicKey))
+	issuer.rsaKey = rotated
+	token := issuer.mint(t, "RS256", "rotated", issuer.claims())
+
+	// the keys were just fetched, so the unknown key doesn't trigger another fetch yet
+	if _, err = provider.VerifyIDToken(token, "nonce-1"); err == nil {
+		t.Error("token with an unknown key was accepted before the keys were refreshed")
+	}
+	if count := issuer.jwksRequestCount(); count != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", count)
+	}
+
+	provider.keysFetched = time.Now().Add(-keyRefreshInterval)
+	if _, err = provider.VerifyIDToken(token, "nonce-1"); err != nil {
+		t.Errorf("token signed with the rotated key: %v", err)
+	}
+	if count := issuer.jwksRequestCount(); count != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", count)
+	}
+
+	// the previous keys are gone after the refresh
+	if _, err = provider.VerifyIDToken(issuer.mint(t, "ES256", "ec", issuer.claims()), "nonce-1"); err == nil ||
+		!strings.Contains(err.Error(), "unknown signing key") {
+		t.Errorf("token signed with a retired key: %v", err)
+	}
+}
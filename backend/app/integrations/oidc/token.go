@@ -0,0 +1,196 @@
+package oidc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far the issuer's clock may be off when checking token times
+const clockSkew = time.Minute
+
+// Claims are the claims of a verified ID token.  Numbers are kept as json.Number.
+type Claims map[string]any
+
+// String returns the claim as text, or "" when it's missing or not a string or number
+func (claims Claims) String(name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		return ""
+	}
+}
+
+// time returns a NumericDate claim, seconds since the epoch
+func (claims Claims) time(name string) (time.Time, bool) {
+	number, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// audience returns the aud claim, which is either a single string or a list of them
+func (claims Claims) audience() []string {
+	switch value := claims["aud"].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var audience []string
+		for _, entry := range value {
+			if text, ok := entry.(string); ok {
+				audience = append(audience, text)
+			}
+		}
+		return audience
+	default:
+		return nil
+	}
+}
+
+func decodeSegment(segment string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// ecdsaCurves is the curve each ES algorithm signs with, ES512 uses P-521
+var ecdsaCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// hashForAlg picks the digest of a JWS algorithm from its size suffix, e.g. 256 in RS256
+func hashForAlg(alg string) (crypto.Hash, bool) {
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		return crypto.SHA256, true
+	case strings.HasSuffix(alg, "384"):
+		return crypto.SHA384, true
+	case strings.HasSuffix(alg, "512"):
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// verifySignature checks an RS*, PS* or ES* signature.  Anything else, including "none" and HMAC, is refused since
+// ID tokens from a public issuer have to be signed with its keys.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	hash, ok := hashForAlg(alg)
+	if !ok || len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	digester := hash.New()
+	digester.Write([]byte(signed))
+	digest := digester.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an RSA key", alg)
+		}
+		if alg[0] == 'R' {
+			return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve.Params().Name != ecdsaCurves[alg] {
+			return fmt.Errorf("%s needs a %s key", alg, ecdsaCurves[alg])
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// VerifyIDToken checks the token's signature against the issuer's keys and that it was issued by the issuer, for
+// this client, isn't expired and carries the nonce sent with the login.
+func (provider *Provider) VerifyIDToken(rawToken string, nonce string) (Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token signature: %w", err)
+	}
+	key, err := provider.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("ID token signature: %w", err)
+	}
+
+	var claims Claims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+
+	if claims.String("iss") != provider.Issuer {
+		return nil, fmt.Errorf("ID token issued by %q", claims.String("iss"))
+	}
+	audience := claims.audience()
+	if !slices.Contains(audience, provider.ClientId) {
+		return nil, errors.New("ID token isn't for this client")
+	}
+	if azp := claims.String("azp"); len(audience) > 1 && azp != provider.ClientId {
+		return nil, fmt.Errorf("ID token authorized for %q", azp)
+	}
+
+	now := time.Now()
+	expires, ok := claims.time("exp")
+	if !ok || !now.Before(expires.Add(clockSkew)) {
+		return nil, errors.New("ID token expired")
+	}
+	if notBefore, ok := claims.time("nbf"); ok && now.Add(clockSkew).Before(notBefore) {
+		return nil, errors.New("ID token not valid yet")
+	}
+	if nonce != "" && claims.String("nonce") != nonce {
+		return nil, errors.New("ID token nonce doesn't match")
+	}
+	return claims, nil
+}
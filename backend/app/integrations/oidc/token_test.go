@@ -0,0 +1,174 @@
+package oidc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.discover(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		alg     string
+		kid     string
+		change  func(claims map[string]any)
+		nonce   string
+		wantErr bool
+	}{
+		{name: "valid RS256", alg: "RS256", kid: "rsa", nonce: "nonce-1"},
+		{name: "valid ES256", alg: "ES256", kid: "ec", nonce: "nonce-1"},
+		{
+			name:  "audience list with this client as azp",
+			alg:   "RS256",
+			kid:   "rsa",
+			nonce: "nonce-1",
+			change: func(claims map[string]any) {
+				claims["aud"] = []string{"other", testClientId}
+				claims["azp"] = testClientId
+			},
+		},
+		{
+			name:   "expired within the clock skew",
+			alg:    "RS256",
+			kid:    "rsa",
+			nonce:  "nonce-1",
+			change: func(claims map[string]any) { claims["exp"] = now.Add(-clockSkew / 2).Unix() },
+		},
+		{
+			name:   "no nonce expected",
+			alg:    "RS256",
+			kid:    "rsa",
+			change: func(claims map[string]any) { delete(claims, "nonce") },
+		},
+		{
+			name:    "wrong issuer",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { claims["iss"] = "https://evil.example" },
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { claims["aud"] = "other" },
+			wantErr: true,
+		},
+		{
+			name:  "audience list with another azp",
+			alg:   "RS256",
+			kid:   "rsa",
+			nonce: "nonce-1",
+			change: func(claims map[string]any) {
+				claims["aud"] = []string{testClientId, "other"}
+				claims["azp"] = "other"
+			},
+			wantErr: true,
+		},
+		{
+			name:    "audience list without azp",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { claims["aud"] = []string{testClientId, "other"} },
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { claims["exp"] = now.Add(-time.Hour).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { delete(claims, "exp") },
+			wantErr: true,
+		},
+		{
+			name:    "not valid yet",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { claims["nbf"] = now.Add(time.Hour).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "nonce mismatch",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-2",
+			wantErr: true,
+		},
+		{
+			name:    "missing nonce",
+			alg:     "RS256",
+			kid:     "rsa",
+			nonce:   "nonce-1",
+			change:  func(claims map[string]any) { delete(claims, "nonce") },
+			wantErr: true,
+		},
+		{name: "alg none", alg: "none", kid: "rsa", nonce: "nonce-1", wantErr: true},
+		{name: "HS256 keyed with the public key", alg: "HS256", kid: "rsa", nonce: "nonce-1", wantErr: true},
+		{name: "RS256 with the EC key id", alg: "RS256", kid: "ec", nonce: "nonce-1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.claims()
+			if test.change != nil {
+				test.change(claims)
+			}
+			token := issuer.mint(t, test.alg, test.kid, claims)
+
+			verified, err := provider.VerifyIDToken(token, test.nonce)
+			if test.wantErr {
+				if err == nil {
+					t.Error("VerifyIDToken accepted the token")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken error: %v", err)
+			}
+			if sub := verified.String("sub"); sub != "user-1" {
+				t.Errorf("sub = %q, want user-1", sub)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenTampered(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.discover(t)
+
+	token := issuer.mint(t, "ES256", "ec", issuer.claims())
+	claims := issuer.claims()
+	claims["sub"] = "admin"
+	forged := strings.Split(issuer.mint(t, "ES256", "ec", claims), ".")
+	parts := strings.Split(token, ".")
+
+	tests := map[string]string{
+		"claims swapped":    parts[0] + "." + forged[1] + "." + parts[2],
+		"signature removed": parts[0] + "." + parts[1] + ".",
+		"missing segment":   parts[0] + "." + parts[1],
+		"bad encoding":      parts[0] + ".!!!." + parts[2],
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := provider.VerifyIDToken(token, "nonce-1"); err == nil {
+				t.Error("VerifyIDToken accepted the token")
+			}
+		})
+	}
+}
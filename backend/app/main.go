@@ -6,6 +6,8 @@ import (
 	//"codejam.io/logging"
 	"codejam.io/server"
 	"github.com/gin-gonic/gin"
+	// embeds the IANA timezone database so timezones validate on hosts without one
+	_ "time/tzdata"
 )
 
 func main() {
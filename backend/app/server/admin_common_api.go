@@ -5,9 +5,33 @@ import (
 	"github.com/emicklei/pgtalk/convert"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 	"net/http"
 )
 
+// GetSessionUserId returns the id of the logged-in session user.  A 401 response is set automatically when
+// there is no session.
+// Returns the user id and true if there is a session user, false otherwise.
+func (server *Server) GetSessionUserId(ctx *gin.Context) (pgtype.UUID, bool) {
+	session := sessions.Default(ctx)
+	userId := session.Get("userId")
+	if userId == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return pgtype.UUID{}, false
+	}
+	return convert.StringToUUID(userId.(string)), true
+}
+
+// OptionalSessionUserId returns the id of the logged-in session user, or an invalid UUID for anonymous requests.
+func (server *Server) OptionalSessionUserId(ctx *gin.Context) pgtype.UUID {
+	session := sessions.Default(ctx)
+	userId := session.Get("userId")
+	if userId == nil {
+		return pgtype.UUID{}
+	}
+	return convert.StringToUUID(userId.(string))
+}
+
 // VerifyAdminAccess checks if the user has admin access by retrieving the session user account
 // and verifying if their role is 'ADMIN'.  Appropriate HTTP responses will be set automatically.
 // Returns true if the user has admin access, false otherwise.
@@ -51,6 +75,21 @@ func (server *Server) UserIsAdmin(userId string) (bool, error) {
 	return false, nil
 }
 
+// UserIsEventOrganizer checks if the user organizes the event, either as the event's organizer or as an admin.
+// Returns true if the user can organize the event, false otherwise.
+func (server *Server) UserIsEventOrganizer(userId pgtype.UUID, eventId pgtype.UUID) (bool, error) {
+	event, err := database.GetEvent(eventId)
+	if err != nil {
+		return false, err
+	}
+
+	if event.OrganizerUserId == userId {
+		return true, nil
+	}
+
+	return server.UserIsAdmin(convert.UUIDToString(userId))
+}
+
 // VerifyUserNotAdmin checks if the user identified by the given userId is not an admin.  This is
 // for scenarios when admin accounts should not allow operations to take place on them, such as
 // moderation actions.
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"codejam.io/database"
+	"errors"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+)
+
+type MergeTagRequest struct {
+	IntoTagId string
+}
+
+// AdminMergeTag folds a duplicate tag into another one, e.g. "golang" into "Go".  The duplicate is deleted and its
+// name keeps working as an alias.
+func (server *Server) AdminMergeTag(ctx *gin.Context) {
+	var request MergeTagRequest
+	if !server.VerifyAdminAccess(ctx) || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	sourceId := convert.StringToUUID(ctx.Param("id"))
+	targetId := convert.StringToUUID(request.IntoTagId)
+	if !sourceId.Valid || !targetId.Valid || sourceId == targetId {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	target, err := database.MergeTags(sourceId, targetId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("User %v merged tag %v into %v", convert.UUIDToString(server.OptionalSessionUserId(ctx)),
+		ctx.Param("id"), target.Name)
+	ctx.JSON(http.StatusOK, target)
+}
+
+func (server *Server) SetupAdminTagRoutes() {
+	logger.Info("Setting up Admin Tag routes...")
+
+	group := server.Gin.Group("/admin/tag")
+	{
+		group.POST("/:id/merge", server.AdminMergeTag)
+	}
+}
@@ -0,0 +1,468 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrz1836/go-sanitize"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxModerationReasonLength = 500
+
+// ModerationRequest is the body of moderation actions that only need a reason
+type ModerationRequest struct {
+	Reason string
+}
+
+type AdminRenameTeamRequest struct {
+	Name   string
+	Reason string
+}
+
+// AdminRosterOverrideRequest keeps a team's roster open for OpenForHours past the event's roster lock, 0 removes
+// the override.
+type AdminRosterOverrideRequest struct {
+	OpenForHours int
+	Reason       string
+}
+
+type AdminUpdateTeamRequest struct {
+	UpdateTeamRequest
+	Reason string
+}
+
+func validateReason(reason string, response *models.FormResponse) {
+	if reason == "" {
+		response.AddError("Reason", "required")
+	} else if len(reason) > maxModerationReasonLength {
+		response.AddError("Reason", fmt.Sprintf("must be at most %d characters", maxModerationReasonLength))
+	}
+}
+
+// VerifyTeamModerator loads the team in the :id route param and checks the session user organizes its event.
+// Appropriate HTTP responses are set automatically.
+// Returns the team, the moderator's user id and true if the user may moderate the team, false otherwise.
+func (server *Server) VerifyTeamModerator(ctx *gin.Context) (database.DBTeam, pgtype.UUID, bool) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return database.DBTeam{}, userId, false
+	}
+
+	team, err := database.GetTeam(convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return team, userId, false
+	}
+
+	isOrganizer, err := server.UserIsEventOrganizer(userId, team.EventId)
+	if err != nil {
+		logger.Error("VerifyTeamModerator: UserIsEventOrganizer error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return team, userId, false
+	}
+	if !isOrganizer {
+		logger.Error("VerifyTeamModerator: unauthorized user: %v", convert.UUIDToString(userId))
+		ctx.Status(http.StatusForbidden)
+		return team, userId, false
+	}
+
+	return team, userId, true
+}
+
+// deserializeReason reads and validates the reason of a moderation request.
+// Appropriate HTTP responses are set automatically.
+// Returns the reason and true if it's valid, false otherwise.
+func (server *Server) deserializeReason(ctx *gin.Context) (string, bool) {
+	var request ModerationRequest
+	if !server.DeserializeRequest(ctx, &request) {
+		return "", false
+	}
+
+	response := models.NewFormResponse()
+	reason := strings.TrimSpace(sanitize.Scripts(request.Reason))
+	validateReason(reason, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return "", false
+	}
+	return reason, true
+}
+
+// auditTeam records a moderation action taken on a team.  It runs in the action's transaction, if the entry
+// can't be written the action is rolled back.
+func auditTeam(tx database.Querier, actorId pgtype.UUID, team database.DBTeam, action string, reason string,
+	details string) error {
+	_, err := database.CreateAuditEntryTx(tx, database.DBAuditEntry{
+		ActorUserId: actorId,
+		TargetType:  database.AuditTargetTeam,
+		TargetId:    team.Id,
+		EventId:     team.EventId,
+		Action:      action,
+		Reason:      reason,
+		Details:     details,
+	})
+	return err
+}
+
+// moderatedTeamInfo is the team as shown in moderation responses.  Organizers aren't on the team, so like other
+// outsiders they don't get the invite code.
+func moderatedTeamInfo(team database.DBTeam) TeamInfo {
+	info := newTeamInfo(team, false)
+	tags, err := database.GetTeamTags(team.Id)
+	if err == nil {
+		info.Tags = tagNames(tags)
+	}
+	return info
+}
+
+// notifyTeamMembers sends the same notification to every member of the team
+func notifyTeamMembers(teamId pgtype.UUID, message string, link string) {
+	members, err := database.GetMembersByTeamId(teamId)
+	if err != nil {
+		return
+	}
+	for _, member := range *members {
+		_, _ = database.CreateNotification(member.Id, message, link)
+	}
+}
+
+func (server *Server) AdminRenameTeam(ctx *gin.Context) {
+	var request AdminRenameTeamRequest
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	oldName := team.Name
+	team.Name = request.Name
+	request.Reason = strings.TrimSpace(sanitize.Scripts(request.Reason))
+
+	sanitizeTeam(&team)
+	server.validateTeamName(team, &response)
+	validateReason(request.Reason, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if team, err = database.UpdateTeamTx(tx, team); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, database.AuditTeamRename, request.Reason,
+			fmt.Sprintf("%q -> %q", oldName, team.Name))
+	})
+	if teamNameConflict(err, &response) {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	} else if err != nil {
+		logger.Error("AdminRenameTeam error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	notifyTeamMembers(team.Id, fmt.Sprintf("An organizer renamed your team %s to %s.", oldName, team.Name),
+		"/team/"+convert.UUIDToString(team.Id))
+
+	response.Data = moderatedTeamInfo(team)
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) AdminUpdateTeam(ctx *gin.Context) {
+	var request AdminUpdateTeamRequest
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	team.Name = request.Name
+	team.Visibility = request.Visibility
+	team.Timezone = request.Timezone
+	team.Technologies = request.Technologies
+	team.Availability = request.Availability
+	team.Description = request.Description
+	team.LookingForMembers = request.LookingForMembers
+	team.WantedRoles = request.WantedRoles
+	request.Reason = strings.TrimSpace(sanitize.Scripts(request.Reason))
+	names := teamTagNames(request.Tags, request.Technologies)
+
+	response := models.NewFormResponse()
+	sanitizeTeam(&team)
+	server.validateTeam(team, &response)
+	validateTags(names, &response)
+	validateReason(request.Reason, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var tags []database.DBTag
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if tags, err = database.ResolveTagsTx(tx, names); err != nil {
+			return err
+		}
+		team.Technologies = strings.Join(tagNames(tags), ", ")
+
+		if team, err = database.UpdateTeamTx(tx, team); err != nil {
+			return err
+		}
+		if err = database.SetTeamTagsTx(tx, team.Id, tags); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, database.AuditTeamEdit, request.Reason, "")
+	})
+	if teamNameConflict(err, &response) {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	} else if err != nil {
+		logger.Error("AdminUpdateTeam error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	teamInfo := newTeamInfo(team, false)
+	teamInfo.Tags = tagNames(tags)
+	response.Data = teamInfo
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) AdminLockTeam(ctx *gin.Context) {
+	server.setTeamLocked(ctx, true)
+}
+
+func (server *Server) AdminUnlockTeam(ctx *gin.Context) {
+	server.setTeamLocked(ctx, false)
+}
+
+func (server *Server) setTeamLocked(ctx *gin.Context, locked bool) {
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok {
+		return
+	}
+	reason, ok := server.deserializeReason(ctx)
+	if !ok {
+		return
+	}
+
+	action := database.AuditTeamUnlock
+	if locked {
+		action = database.AuditTeamLock
+	}
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if team, err = database.SetTeamLockedTx(tx, team.Id, locked); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, action, reason, "")
+	})
+	if err != nil {
+		logger.Error("setTeamLocked error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, moderatedTeamInfo(team))
+}
+
+// AdminSetRosterOverride lets a team add or drop members after the event's roster lock, e.g. to replace someone
+// who dropped out.
+func (server *Server) AdminSetRosterOverride(ctx *gin.Context) {
+	var request AdminRosterOverrideRequest
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	request.Reason = strings.TrimSpace(sanitize.Scripts(request.Reason))
+	if request.OpenForHours < 0 {
+		response.AddError("OpenForHours", "must not be negative")
+	}
+	validateReason(request.Reason, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var openUntil pgtype.Timestamptz
+	details := "override removed"
+	if request.OpenForHours > 0 {
+		openUntil = pgtype.Timestamptz{
+			Time:  time.Now().UTC().Add(time.Duration(request.OpenForHours) * time.Hour),
+			Valid: true,
+		}
+		details = "open until " + openUntil.Time.Format(time.RFC3339)
+	}
+
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if team, err = database.SetTeamRosterOpenUntilTx(tx, team.Id, openUntil); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, database.AuditTeamRosterOpen, request.Reason, details)
+	})
+	if err != nil {
+		logger.Error("AdminSetRosterOverride error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	response.Data = moderatedTeamInfo(team)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// AdminDisbandTeam deletes the team after letting its members know why.
+func (server *Server) AdminDisbandTeam(ctx *gin.Context) {
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok {
+		return
+	}
+	reason, ok := server.deserializeReason(ctx)
+	if !ok {
+		return
+	}
+
+	// the memberships go with the team, so find out who to notify first
+	members, err := database.GetMembersByTeamId(team.Id)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	teamInfo := moderatedTeamInfo(team)
+
+	err = database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if team, err = database.DeleteTeamTx(tx, team.Id); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, database.AuditTeamDisband, reason, fmt.Sprintf("name: %q", team.Name))
+	})
+	if err != nil {
+		logger.Error("AdminDisbandTeam error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	for _, member := range *members {
+		_, _ = database.CreateNotification(member.Id,
+			fmt.Sprintf("Your team %s was disbanded by an organizer: %s", team.Name, reason), "/team")
+	}
+	logger.Info("User %v disbanded team %v", convert.UUIDToString(actorId), convert.UUIDToString(team.Id))
+	ctx.JSON(http.StatusOK, teamInfo)
+}
+
+// AdminRemoveTeamMember removes any member, including the owner.  When the owner is removed the longest standing
+// member takes over, and a team left without members is disbanded.
+func (server *Server) AdminRemoveTeamMember(ctx *gin.Context) {
+	team, actorId, ok := server.VerifyTeamModerator(ctx)
+	if !ok {
+		return
+	}
+	reason, ok := server.deserializeReason(ctx)
+	if !ok {
+		return
+	}
+
+	memberId := convert.StringToUUID(ctx.Param("userId"))
+	var leave database.DBTeamLeave
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if leave, err = database.LeaveTeamTx(tx, team.Id, memberId, false); err != nil {
+			return err
+		}
+		return auditTeam(tx, actorId, team, database.AuditTeamRemoveMember, reason,
+			"user: "+convert.UUIDToString(memberId))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			logger.Error("AdminRemoveTeamMember error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	_, _ = database.CreateNotification(memberId,
+		fmt.Sprintf("You were removed from %s by an organizer: %s", team.Name, reason), "/team")
+
+	ctx.JSON(http.StatusOK, LeaveTeamResponse{Disbanded: leave.Disbanded})
+}
+
+// GetTeamAuditLog lists the moderation actions taken on a team.  Disbanded teams keep their audit trail, so access
+// is checked against the event recorded with the entries when the team no longer exists.
+func (server *Server) GetTeamAuditLog(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	teamId := convert.StringToUUID(ctx.Param("id"))
+
+	entries, err := database.GetAuditEntries(database.AuditTargetTeam, teamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	var eventId pgtype.UUID
+	team, err := database.GetTeam(teamId)
+	if err == nil {
+		eventId = team.EventId
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	} else if len(entries) > 0 {
+		eventId = entries[0].EventId
+	}
+	if !eventId.Valid {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+
+	isOrganizer, err := server.UserIsEventOrganizer(userId, eventId)
+	if err != nil {
+		logger.Error("GetTeamAuditLog: UserIsEventOrganizer error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if !isOrganizer {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	if entries == nil {
+		entries = []database.DBAuditEntryInfo{}
+	}
+	ctx.JSON(http.StatusOK, entries)
+}
+
+func (server *Server) SetupAdminTeamRoutes() {
+	logger.Info("Setting up Admin Team routes...")
+
+	group := server.Gin.Group("/admin/team")
+	{
+		group.PUT("/:id", server.AdminUpdateTeam)
+		group.PUT("/:id/name/", server.AdminRenameTeam)
+		group.PUT("/:id/lock/", server.AdminLockTeam)
+		group.PUT("/:id/unlock/", server.AdminUnlockTeam)
+		group.PUT("/:id/roster/", server.AdminSetRosterOverride)
+		group.DELETE("/:id", server.AdminDisbandTeam)
+		group.DELETE("/:id/member/:userId", server.AdminRemoveTeamMember)
+		group.GET("/:id/audit", server.GetTeamAuditLog)
+	}
+}
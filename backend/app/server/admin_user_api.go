@@ -2,9 +2,11 @@ package server
 
 import (
 	"codejam.io/database"
+	"codejam.io/server/models"
 	"github.com/emicklei/pgtalk/convert"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
 func (server *Server) GetAllUsers(ctx *gin.Context) {
@@ -49,6 +51,15 @@ func (server *Server) PutDisplayName(ctx *gin.Context) {
 	if server.VerifyAdminAccess(ctx) &&
 		server.VerifyUserNotAdmin(ctx, userIdParam) &&
 		server.DeserializeRequest(ctx, &request) {
+		// names set by admins follow the same rules as the ones users pick
+		response := models.NewFormResponse()
+		request.DisplayName = strings.TrimSpace(request.DisplayName)
+		server.validateDisplayName(request.DisplayName, &response)
+		if len(response.Errors) > 0 {
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+
 		user, err := database.SetDisplayName(convert.StringToUUID(userIdParam), request.DisplayName)
 		if err != nil {
 			logger.Error("SetDisplayName error: %v", err)
@@ -83,7 +94,7 @@ func (server *Server) PutBan(ctx *gin.Context) {
 	userIdParam := ctx.Param("id")
 	if server.VerifyAdminAccess(ctx) &&
 		server.VerifyUserNotAdmin(ctx, userIdParam) {
-		user, err := database.SetAccountStatus(convert.StringToUUID(userIdParam), "BANNED")
+		user, err := database.SetAccountStatus(convert.StringToUUID(userIdParam), database.AccountBanned)
 		if err != nil {
 			logger.Error("PutBan: SetAccountStatus error: %v", err)
 			ctx.Status(http.StatusInternalServerError)
@@ -97,7 +108,7 @@ func (server *Server) PutUnban(ctx *gin.Context) {
 	userIdParam := ctx.Param("id")
 	if server.VerifyAdminAccess(ctx) &&
 		server.VerifyUserNotAdmin(ctx, userIdParam) {
-		user, err := database.SetAccountStatus(convert.StringToUUID(userIdParam), "ACTIVE")
+		user, err := database.SetAccountStatus(convert.StringToUUID(userIdParam), database.AccountActive)
 		if err != nil {
 			logger.Error("PutUnban: SetAccountStatus error: %v", err)
 			ctx.Status(http.StatusInternalServerError)
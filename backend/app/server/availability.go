@@ -0,0 +1,215 @@
+package server
+
+import (
+	"codejam.io/database"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"sort"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// TeamOverlap is when every member who has set their availability is available at the same time, in UTC and in
+// each member's own timezone.  Members without availability don't limit the overlap and are listed separately.
+type TeamOverlap struct {
+	Utc                 []database.AvailabilityWindow
+	WeeklyHours         float64
+	Members             []MemberOverlap
+	MembersWithoutTimes []pgtype.UUID
+}
+
+type MemberOverlap struct {
+	UserId      pgtype.UUID
+	DisplayName string
+	Timezone    string
+	Windows     []database.AvailabilityWindow
+}
+
+// weekInterval is a range of minutes since Sunday 00:00, End is exclusive
+type weekInterval struct {
+	Start int
+	End   int
+}
+
+// validTimezone reports whether the name is in the IANA timezone database
+func validTimezone(timezone string) bool {
+	if timezone == "" || timezone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+// parseClock turns "15:04" into minutes since midnight, "24:00" is allowed as the end of the day
+func parseClock(clock string) (int, bool) {
+	if clock == "24:00" {
+		return minutesPerDay, true
+	}
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return parsed.Hour()*60 + parsed.Minute(), true
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// weekStart is midnight of the Sunday starting the week that contains now, in the location
+func weekStart(now time.Time, location *time.Location) time.Time {
+	local := now.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day()-int(local.Weekday()), 0, 0, 0, 0, location)
+}
+
+// minuteOfWeek is how many minutes into its week (Sunday 00:00) the time is, in the time's own location
+func minuteOfWeek(t time.Time) int {
+	return int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute()
+}
+
+// addWrapped adds an interval of the given length starting at a minute of the week, splitting it when it runs past
+// the end of the week.
+func addWrapped(intervals []weekInterval, start int, length int) []weekInterval {
+	start = ((start % minutesPerWeek) + minutesPerWeek) % minutesPerWeek
+	end := start + length
+	if end <= minutesPerWeek {
+		return append(intervals, weekInterval{start, end})
+	}
+	return append(intervals, weekInterval{start, minutesPerWeek}, weekInterval{0, end - minutesPerWeek})
+}
+
+// mergeIntervals sorts the intervals and joins the ones that overlap or touch
+func mergeIntervals(intervals []weekInterval) []weekInterval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start < intervals[j].Start })
+	var merged []weekInterval
+	for _, interval := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && interval.Start <= merged[last].End {
+			merged[last].End = max(merged[last].End, interval.End)
+		} else {
+			merged = append(merged, interval)
+		}
+	}
+	return merged
+}
+
+// intersectIntervals returns the ranges covered by both merged interval lists
+func intersectIntervals(a []weekInterval, b []weekInterval) []weekInterval {
+	var result []weekInterval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start := max(a[i].Start, b[j].Start)
+		end := min(a[i].End, b[j].End)
+		if start < end {
+			result = append(result, weekInterval{start, end})
+		}
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// utcIntervals converts weekly windows in a location to UTC, using the offsets in effect during the week of now.
+func utcIntervals(windows []database.AvailabilityWindow, location *time.Location, now time.Time) []weekInterval {
+	week := weekStart(now, location)
+	var intervals []weekInterval
+	for _, window := range windows {
+		startMinute, okStart := parseClock(window.Start)
+		endMinute, okEnd := parseClock(window.End)
+		if !okStart || !okEnd || endMinute <= startMinute {
+			continue
+		}
+		// time.Date normalizes the day and minute overflow, and applies the offset in effect at that moment
+		start := time.Date(week.Year(), week.Month(), week.Day()+window.Day, 0, startMinute, 0, 0, location)
+		end := time.Date(week.Year(), week.Month(), week.Day()+window.Day, 0, endMinute, 0, 0, location)
+		intervals = addWrapped(intervals, minuteOfWeek(start.UTC()), int(end.Sub(start).Minutes()))
+	}
+	return mergeIntervals(intervals)
+}
+
+// localIntervals converts UTC week intervals to the location's wall clock during the week of now.
+func localIntervals(intervals []weekInterval, location *time.Location, now time.Time) []weekInterval {
+	week := weekStart(now, time.UTC)
+	var local []weekInterval
+	for _, interval := range intervals {
+		start := week.Add(time.Duration(interval.Start) * time.Minute).In(location)
+		end := week.Add(time.Duration(interval.End) * time.Minute).In(location)
+		// the wall clock moves by the offset change when the interval spans a daylight saving transition
+		_, startOffset := start.Zone()
+		_, endOffset := end.Zone()
+		local = addWrapped(local, minuteOfWeek(start), interval.End-interval.Start+(endOffset-startOffset)/60)
+	}
+	return mergeIntervals(local)
+}
+
+// dayWindows splits week intervals at midnight into windows
+func dayWindows(intervals []weekInterval) []database.AvailabilityWindow {
+	windows := []database.AvailabilityWindow{}
+	for _, interval := range intervals {
+		for start := interval.Start; start < interval.End; {
+			day := start / minutesPerDay
+			end := min(interval.End, (day+1)*minutesPerDay)
+			windows = append(windows, database.AvailabilityWindow{
+				Day:   day,
+				Start: formatClock(start - day*minutesPerDay),
+				End:   formatClock(end - day*minutesPerDay),
+			})
+			start = end
+		}
+	}
+	return windows
+}
+
+// teamOverlap works out when the members are available together during the week of now.
+func teamOverlap(members []database.DBMemberAvailability, now time.Time) TeamOverlap {
+	overlap := TeamOverlap{
+		Utc:                 []database.AvailabilityWindow{},
+		Members:             []MemberOverlap{},
+		MembersWithoutTimes: []pgtype.UUID{},
+	}
+
+	var common []weekInterval
+	locations := make(map[pgtype.UUID]*time.Location)
+	first := true
+	for _, member := range members {
+		location, err := time.LoadLocation(member.Timezone)
+		if member.Timezone == "" || err != nil || len(member.Windows) == 0 {
+			overlap.MembersWithoutTimes = append(overlap.MembersWithoutTimes, member.UserId)
+			continue
+		}
+		locations[member.UserId] = location
+
+		intervals := utcIntervals(member.Windows, location, now)
+		if first {
+			common = intervals
+			first = false
+		} else {
+			common = intersectIntervals(common, intervals)
+		}
+	}
+
+	for _, interval := range common {
+		overlap.WeeklyHours += float64(interval.End-interval.Start) / 60
+	}
+	overlap.Utc = dayWindows(common)
+
+	for _, member := range members {
+		location, ok := locations[member.UserId]
+		if !ok {
+			continue
+		}
+		overlap.Members = append(overlap.Members, MemberOverlap{
+			UserId:      member.UserId,
+			DisplayName: member.DisplayName,
+			Timezone:    member.Timezone,
+			Windows:     dayWindows(localIntervals(common, location, now)),
+		})
+	}
+	return overlap
+}
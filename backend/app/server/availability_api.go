@@ -0,0 +1,104 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+	"strings"
+)
+
+const maxAvailabilityWindows = 50
+
+type PutAvailabilityRequest struct {
+	Timezone string
+	Windows  []database.AvailabilityWindow
+}
+
+func validateAvailability(availability database.DBUserAvailability, response *models.FormResponse) {
+	if !validTimezone(availability.Timezone) {
+		response.AddError("Timezone", "must be an IANA timezone such as Europe/Berlin")
+	}
+	if len(availability.Windows) > maxAvailabilityWindows {
+		response.AddError("Windows", fmt.Sprintf("at most %d windows", maxAvailabilityWindows))
+		return
+	}
+	for _, window := range availability.Windows {
+		start, okStart := parseClock(window.Start)
+		end, okEnd := parseClock(window.End)
+		if window.Day < 0 || window.Day > 6 {
+			response.AddError("Windows", "Day must be between 0 (Sunday) and 6 (Saturday)")
+			return
+		}
+		if !okStart || !okEnd || start == minutesPerDay {
+			response.AddError("Windows", "Start and End must be times like 09:30")
+			return
+		}
+		if end <= start {
+			response.AddError("Windows", "End must be after Start, split windows that go past midnight")
+			return
+		}
+	}
+}
+
+func (server *Server) GetAvailability(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	availability, err := database.GetUserAvailability(userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, availability)
+}
+
+// PutAvailability sets the session user's timezone and weekly availability, which is used to work out when their
+// teams can meet.
+func (server *Server) PutAvailability(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request PutAvailabilityRequest
+	if !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	// never nil, the column doesn't allow NULL
+	windows := []database.AvailabilityWindow{}
+	for _, window := range request.Windows {
+		window.Start = strings.TrimSpace(window.Start)
+		window.End = strings.TrimSpace(window.End)
+		windows = append(windows, window)
+	}
+	availability := database.DBUserAvailability{
+		UserId:   userId,
+		Timezone: strings.TrimSpace(request.Timezone),
+		Windows:  windows,
+	}
+
+	response := models.NewFormResponse()
+	validateAvailability(availability, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	availability, err := database.SaveUserAvailability(availability)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	response.Data = availability
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"codejam.io/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"slices"
+	"testing"
+	"time"
+)
+
+// weekMinute is the minute of the week for a day (0 is Sunday) and wall clock time
+func weekMinute(day int, hour int, minute int) int {
+	return day*minutesPerDay + hour*60 + minute
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return location
+}
+
+func testUserId(n byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{n}, Valid: true}
+}
+
+func TestUtcIntervals(t *testing.T) {
+	winter := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	// clocks in New York go forward at 02:00 on Sunday 2024-03-10
+	springForward := time.Date(2024, time.March, 12, 12, 0, 0, 0, time.UTC)
+	weekBefore := time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		timezone string
+		windows  []database.AvailabilityWindow
+		now      time.Time
+		want     []weekInterval
+	}{
+		{
+			name:     "utc is unchanged",
+			timezone: "UTC",
+			windows:  []database.AvailabilityWindow{{Day: 1, Start: "09:00", End: "17:00"}},
+			now:      winter,
+			want:     []weekInterval{{weekMinute(1, 9, 0), weekMinute(1, 17, 0)}},
+		},
+		{
+			name:     "window crosses midnight in utc",
+			timezone: "Asia/Tokyo",
+			windows:  []database.AvailabilityWindow{{Day: 1, Start: "06:00", End: "12:00"}},
+			now:      winter,
+			want:     []weekInterval{{weekMinute(0, 21, 0), weekMinute(1, 3, 0)}},
+		},
+		{
+			name:     "window wraps back to the end of the week",
+			timezone: "Asia/Tokyo",
+			windows:  []database.AvailabilityWindow{{Day: 0, Start: "08:00", End: "12:00"}},
+			now:      winter,
+			want:     []weekInterval{{0, weekMinute(0, 3, 0)}, {weekMinute(6, 23, 0), minutesPerWeek}},
+		},
+		{
+			name:     "window wraps forward to the start of the week",
+			timezone: "America/New_York",
+			windows:  []database.AvailabilityWindow{{Day: 6, Start: "22:00", End: "24:00"}},
+			now:      winter,
+			want:     []weekInterval{{weekMinute(0, 3, 0), weekMinute(0, 5, 0)}},
+		},
+		{
+			name:     "touching windows are merged",
+			timezone: "UTC",
+			windows: []database.AvailabilityWindow{
+				{Day: 2, Start: "12:00", End: "24:00"},
+				{Day: 3, Start: "00:00", End: "06:00"},
+			},
+			now:  winter,
+			want: []weekInterval{{weekMinute(2, 12, 0), weekMinute(3, 6, 0)}},
+		},
+		{
+			name:     "invalid windows are skipped",
+			timezone: "UTC",
+			windows: []database.AvailabilityWindow{
+				{Day: 1, Start: "17:00", End: "09:00"},
+				{Day: 1, Start: "9am", End: "17:00"},
+				{Day: 1, Start: "10:00", End: "10:00"},
+			},
+			now: winter,
+		},
+		{
+			name:     "window spanning the daylight saving change is an hour shorter",
+			timezone: "America/New_York",
+			windows:  []database.AvailabilityWindow{{Day: 0, Start: "00:00", End: "04:00"}},
+			now:      springForward,
+			want:     []weekInterval{{weekMinute(0, 5, 0), weekMinute(0, 8, 0)}},
+		},
+		{
+			name:     "offset after the daylight saving change",
+			timezone: "America/New_York",
+			windows:  []database.AvailabilityWindow{{Day: 1, Start: "09:00", End: "10:00"}},
+			now:      springForward,
+			want:     []weekInterval{{weekMinute(1, 13, 0), weekMinute(1, 14, 0)}},
+		},
+		{
+			name:     "offset before the daylight saving change",
+			timezone: "America/New_York",
+			windows:  []database.AvailabilityWindow{{Day: 1, Start: "09:00", End: "10:00"}},
+			now:      weekBefore,
+			want:     []weekInterval{{weekMinute(1, 14, 0), weekMinute(1, 15, 0)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := utcIntervals(test.windows, mustLoadLocation(t, test.timezone), test.now)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("utcIntervals = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLocalIntervals(t *testing.T) {
+	winter := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	springForward := time.Date(2024, time.March, 12, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timezone  string
+		intervals []weekInterval
+		now       time.Time
+		want      []weekInterval
+	}{
+		{
+			name:      "interval crossing midnight in utc",
+			timezone:  "Asia/Tokyo",
+			intervals: []weekInterval{{weekMinute(0, 21, 0), weekMinute(1, 3, 0)}},
+			now:       winter,
+			want:      []weekInterval{{weekMinute(1, 6, 0), weekMinute(1, 12, 0)}},
+		},
+		{
+			name:      "intervals split at the end of the week are joined",
+			timezone:  "Asia/Tokyo",
+			intervals: []weekInterval{{0, weekMinute(0, 3, 0)}, {weekMinute(6, 23, 0), minutesPerWeek}},
+			now:       winter,
+			want:      []weekInterval{{weekMinute(0, 8, 0), weekMinute(0, 12, 0)}},
+		},
+		{
+			name:      "interval wraps back to the end of the week",
+			timezone:  "America/Los_Angeles",
+			intervals: []weekInterval{{weekMinute(0, 6, 0), weekMinute(0, 10, 0)}},
+			now:       winter,
+			want:      []weekInterval{{0, weekMinute(0, 2, 0)}, {weekMinute(6, 22, 0), minutesPerWeek}},
+		},
+		{
+			name:      "wall clock skips the hour lost to daylight saving",
+			timezone:  "America/New_York",
+			intervals: []weekInterval{{weekMinute(0, 5, 0), weekMinute(0, 8, 0)}},
+			now:       springForward,
+			want:      []weekInterval{{0, weekMinute(0, 4, 0)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := localIntervals(test.intervals, mustLoadLocation(t, test.timezone), test.now)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("localIntervals = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTeamOverlap(t *testing.T) {
+	winter := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	monday := func(start string, end string) []database.AvailabilityWindow {
+		return []database.AvailabilityWindow{{Day: 1, Start: start, End: end}}
+	}
+
+	t.Run("members in different zones", func(t *testing.T) {
+		overlap := teamOverlap([]database.DBMemberAvailability{
+			{UserId: testUserId(1), DisplayName: "ny", Timezone: "America/New_York", Windows: monday("09:00", "17:00")},
+			{UserId: testUserId(2), DisplayName: "berlin", Timezone: "Europe/Berlin", Windows: monday("09:00", "17:00")},
+			{UserId: testUserId(3), DisplayName: "unset", Timezone: "Europe/Berlin"},
+			{UserId: testUserId(4), DisplayName: "bad zone", Timezone: "Not/AZone", Windows: monday("09:00", "17:00")},
+		}, winter)
+
+		wantUtc := []database.AvailabilityWindow{{Day: 1, Start: "14:00", End: "16:00"}}
+		if !slices.Equal(overlap.Utc, wantUtc) {
+			t.Errorf("Utc = %v, want %v", overlap.Utc, wantUtc)
+		}
+		if overlap.WeeklyHours != 2 {
+			t.Errorf("WeeklyHours = %v, want 2", overlap.WeeklyHours)
+		}
+		if want := []pgtype.UUID{testUserId(3), testUserId(4)}; !slices.Equal(overlap.MembersWithoutTimes, want) {
+			t.Errorf("MembersWithoutTimes = %v, want %v", overlap.MembersWithoutTimes, want)
+		}
+
+		wantMembers := map[string][]database.AvailabilityWindow{
+			"ny":     monday("09:00", "11:00"),
+			"berlin": monday("15:00", "17:00"),
+		}
+		if len(overlap.Members) != len(wantMembers) {
+			t.Fatalf("got %d members, want %d", len(overlap.Members), len(wantMembers))
+		}
+		for _, member := range overlap.Members {
+			if want := wantMembers[member.DisplayName]; !slices.Equal(member.Windows, want) {
+				t.Errorf("%s windows = %v, want %v", member.DisplayName, member.Windows, want)
+			}
+		}
+	})
+
+	t.Run("overlap on different days in each zone", func(t *testing.T) {
+		overlap := teamOverlap([]database.DBMemberAvailability{
+			{UserId: testUserId(1), DisplayName: "tokyo", Timezone: "Asia/Tokyo", Windows: monday("06:00", "10:00")},
+			{UserId: testUserId(2), DisplayName: "la", Timezone: "America/Los_Angeles",
+				Windows: []database.AvailabilityWindow{{Day: 0, Start: "13:00", End: "16:00"}}},
+		}, winter)
+
+		wantUtc := []database.AvailabilityWindow{{Day: 0, Start: "21:00", End: "24:00"}}
+		if !slices.Equal(overlap.Utc, wantUtc) {
+			t.Errorf("Utc = %v, want %v", overlap.Utc, wantUtc)
+		}
+		wantMembers := map[string][]database.AvailabilityWindow{
+			"tokyo": monday("06:00", "09:00"),
+			"la":    {{Day: 0, Start: "13:00", End: "16:00"}},
+		}
+		for _, member := range overlap.Members {
+			if want := wantMembers[member.DisplayName]; !slices.Equal(member.Windows, want) {
+				t.Errorf("%s windows = %v, want %v", member.DisplayName, member.Windows, want)
+			}
+		}
+	})
+
+	t.Run("no common time", func(t *testing.T) {
+		overlap := teamOverlap([]database.DBMemberAvailability{
+			{UserId: testUserId(1), DisplayName: "a", Timezone: "UTC", Windows: monday("09:00", "12:00")},
+			{UserId: testUserId(2), DisplayName: "b", Timezone: "UTC", Windows: monday("12:00", "15:00")},
+		}, winter)
+
+		if len(overlap.Utc) != 0 || overlap.WeeklyHours != 0 {
+			t.Errorf("got %v (%v hours), want no overlap", overlap.Utc, overlap.WeeklyHours)
+		}
+		for _, member := range overlap.Members {
+			if len(member.Windows) != 0 {
+				t.Errorf("%s windows = %v, want none", member.DisplayName, member.Windows)
+			}
+		}
+	})
+}
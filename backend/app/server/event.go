@@ -23,6 +23,9 @@ func sanitizeEvent(event *database.DBEvent) {
 	event.Description = sanitize.Scripts(event.Description)
 	event.Timeline = sanitize.Scripts(event.Timeline)
 	event.Rules = sanitize.Scripts(event.Rules)
+	if event.RosterLockPolicy == "" {
+		event.RosterLockPolicy = database.RosterLockAtStart
+	}
 }
 
 func validateEvent(event database.DBEvent, response *models.FormResponse) {
@@ -30,6 +33,16 @@ func validateEvent(event database.DBEvent, response *models.FormResponse) {
 	if strings.Trim(event.Title, " ") == "" {
 		response.AddError("Title", "required")
 	}
+
+	switch event.RosterLockPolicy {
+	case database.RosterLockAtStart, database.RosterLockNever:
+	case database.RosterLockAfterHours:
+		if event.RosterLockHours <= 0 {
+			response.AddError("RosterLockHours", "must be at least 1")
+		}
+	default:
+		response.AddError("RosterLockPolicy", "must be AT_START, AFTER_HOURS or NEVER")
+	}
 }
 
 func (server *Server) GetAllEvents(ctx *gin.Context) {
@@ -162,5 +175,10 @@ func (server *Server) SetupEventRoutes() {
 		group.PUT("/:id", server.PutEvent)
 		group.POST("/", server.PostEvent)
 		group.GET("/statuses", server.GetStatuses)
+		group.GET("/:id/solo", server.GetSoloProfile)
+		group.PUT("/:id/solo", server.PutSoloProfile)
+		group.DELETE("/:id/solo", server.DeleteSoloProfile)
+		group.GET("/:id/solo/matches", server.GetSoloMatches)
+		group.POST("/:id/solo/autogroup", server.AutoGroupSolos)
 	}
 }
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"codejam.io/database"
+	"errors"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+)
+
+// GetIdentities lists the provider accounts the session user can log in with.  More are added via /oauth/link.
+func (server *Server) GetIdentities(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	identities, err := database.GetUserIdentities(userId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []database.DBUserIdentity{}
+	}
+	ctx.JSON(http.StatusOK, identities)
+}
+
+// DeleteIdentity unlinks a provider account from the session user.  The last one can't be removed, the user would have
+// no way to log in.
+func (server *Server) DeleteIdentity(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	identityId := convert.StringToUUID(ctx.Param("id"))
+	_, err := database.UnlinkUserIdentity(userId, identityId)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		// tell apart a missing identity from the last one
+		identities, err := database.GetUserIdentities(userId)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		for _, identity := range identities {
+			if identity.Id == identityId {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "can't unlink the only account you log in with"})
+				return
+			}
+		}
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
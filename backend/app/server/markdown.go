@@ -0,0 +1,22 @@
+package server
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders GitHub flavoured markdown.  Raw HTML in the source is dropped and dangerous link targets like
+// javascript: URLs are removed, since goldmark's unsafe mode is left off.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// renderMarkdown converts user supplied markdown into HTML that's safe to display
+func renderMarkdown(source string) string {
+	var buffer bytes.Buffer
+	err := markdown.Convert([]byte(source), &buffer)
+	if err != nil {
+		logger.Error("renderMarkdown error: %v", err)
+		return ""
+	}
+	return buffer.String()
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"codejam.io/database"
+	"math"
+	"time"
+)
+
+const (
+	// hours of a participant's day assumed to be usable for the jam when comparing timezones
+	workingHours = 12.0
+	// how much timezone overlap and technology fit contribute to a match score
+	timezoneWeight   = 0.6
+	technologyWeight = 0.4
+	// score used when one side hasn't said anything about a criteria
+	neutralScore = 0.5
+)
+
+// utcOffsetHours returns the current UTC offset of an IANA timezone name
+func utcOffsetHours(timezone string) (float64, bool) {
+	if timezone == "" {
+		return 0, false
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return 0, false
+	}
+	_, offset := time.Now().In(location).Zone()
+	return float64(offset) / 3600, true
+}
+
+// timezoneScore rates from 0 to 1 how much two working days overlap
+func timezoneScore(timezoneA string, timezoneB string) float64 {
+	offsetA, okA := utcOffsetHours(timezoneA)
+	offsetB, okB := utcOffsetHours(timezoneB)
+	if !okA || !okB {
+		return neutralScore
+	}
+
+	difference := math.Abs(offsetA - offsetB)
+	if difference > 12 {
+		difference = 24 - difference
+	}
+	return math.Max(0, workingHours-difference) / workingHours
+}
+
+// canonicalTechnologies turns technology names into tag slugs, following the tag aliases, so a profile listing
+// "Golang" matches a team tagged "Go".  aliases maps an alias to the slug of its tag, as GetTagAliases returns.
+func canonicalTechnologies(technologies []string, aliases map[string]string) []string {
+	canonical := make([]string, 0, len(technologies))
+	for _, technology := range technologies {
+		slug := database.NormalizeTag(technology)
+		if tagSlug, ok := aliases[slug]; ok {
+			slug = tagSlug
+		}
+		if slug != "" {
+			canonical = append(canonical, slug)
+		}
+	}
+	return canonical
+}
+
+// technologySet normalizes technology names the way tags are, so "Go" and " go" are the same
+func technologySet(technologies []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, technology := range technologies {
+		technology = database.NormalizeTag(technology)
+		if technology != "" {
+			set[technology] = true
+		}
+	}
+	return set
+}
+
+// technologyScore rates from 0 to 1 how similar two sets of technologies are (Jaccard similarity)
+func technologyScore(technologiesA []string, technologiesB []string) float64 {
+	setA := technologySet(technologiesA)
+	setB := technologySet(technologiesB)
+	if len(setA) == 0 && len(setB) == 0 {
+		return neutralScore
+	}
+
+	shared := 0
+	for technology := range setA {
+		if setB[technology] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// matchScore combines timezone overlap and technology fit into a score from 0 to 1
+func matchScore(timezoneA string, technologiesA []string, timezoneB string, technologiesB []string) float64 {
+	return timezoneWeight*timezoneScore(timezoneA, timezoneB) +
+		technologyWeight*technologyScore(technologiesA, technologiesB)
+}
+
+// groupSolos greedily forms groups of up to groupSize solo participants.  Each group starts with the longest
+// waiting participant and adds whoever scores best against the group so far, so the result only depends on the
+// order of the profiles.  Participants that would end up alone are returned as leftovers.  Technologies are compared
+// through the tag aliases.
+func groupSolos(profiles []database.DBSoloProfileInfo, groupSize int,
+	aliases map[string]string) ([][]database.DBSoloProfileInfo, []database.DBSoloProfileInfo) {
+	var groups [][]database.DBSoloProfileInfo
+	var leftovers []database.DBSoloProfileInfo
+
+	technologies := make([][]string, len(profiles))
+	for i, profile := range profiles {
+		technologies[i] = canonicalTechnologies(profile.Technologies, aliases)
+	}
+
+	assigned := make([]bool, len(profiles))
+	for seed := range profiles {
+		if assigned[seed] {
+			continue
+		}
+		assigned[seed] = true
+		group := []database.DBSoloProfileInfo{profiles[seed]}
+		members := []int{seed}
+
+		for len(group) < groupSize {
+			best := -1
+			bestScore := -1.0
+			for candidate := range profiles {
+				if assigned[candidate] {
+					continue
+				}
+				score := 0.0
+				for _, member := range members {
+					score += matchScore(profiles[member].Timezone, technologies[member],
+						profiles[candidate].Timezone, technologies[candidate])
+				}
+				score /= float64(len(members))
+				if score > bestScore {
+					best = candidate
+					bestScore = score
+				}
+			}
+			if best < 0 {
+				break
+			}
+			assigned[best] = true
+			group = append(group, profiles[best])
+			members = append(members, best)
+		}
+
+		if len(group) > 1 {
+			groups = append(groups, group)
+		} else {
+			leftovers = append(leftovers, group...)
+		}
+	}
+
+	return groups, leftovers
+}
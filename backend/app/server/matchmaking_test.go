@@ -0,0 +1,191 @@
+package server
+
+import (
+	"codejam.io/database"
+	"math"
+	"slices"
+	"testing"
+)
+
+func soloProfile(name string, timezone string, technologies ...string) database.DBSoloProfileInfo {
+	return database.DBSoloProfileInfo{
+		DBSoloProfile: database.DBSoloProfile{Timezone: timezone, Technologies: technologies},
+		DisplayName:   name,
+	}
+}
+
+func profileNames(profiles []database.DBSoloProfileInfo) []string {
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.DisplayName)
+	}
+	return names
+}
+
+func TestMatchScore(t *testing.T) {
+	// zones without daylight saving time, so the scores don't depend on when the test runs
+	tests := []struct {
+		name          string
+		timezoneA     string
+		technologiesA []string
+		timezoneB     string
+		technologiesB []string
+		want          float64
+	}{
+		{"identical", "UTC", []string{"Go"}, "UTC", []string{"Go"}, 1},
+		{"nothing known", "", nil, "", nil, neutralScore},
+		{"unknown timezone", "Not/AZone", []string{"Go"}, "UTC", []string{"Go"}, timezoneWeight*neutralScore + technologyWeight},
+		{"technologies ignore case and spaces", "UTC", []string{" go ", "RUST"}, "UTC", []string{"Go", "rust"}, 1},
+		{"technologies are normalized like tags", "UTC", []string{"Ruby  on   Rails"}, "UTC", []string{"ruby on rails"}, 1},
+		{"partial overlap", "UTC", []string{"go"}, "Asia/Tokyo", []string{"Go", "Rust"},
+			timezoneWeight*3/12 + technologyWeight*0.5},
+		{"difference wraps around the day", "UTC", nil, "Pacific/Kiritimati", nil,
+			timezoneWeight*2/12 + technologyWeight*neutralScore},
+		{"no working hours in common", "Etc/GMT+12", []string{"Go"}, "UTC", []string{"Java"}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := matchScore(test.timezoneA, test.technologiesA, test.timezoneB, test.technologiesB)
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("matchScore = %v, want %v", got, test.want)
+			}
+			reversed := matchScore(test.timezoneB, test.technologiesB, test.timezoneA, test.technologiesA)
+			if math.Abs(got-reversed) > 1e-9 {
+				t.Errorf("matchScore isn't symmetric: %v and %v", got, reversed)
+			}
+		})
+	}
+}
+
+func TestCanonicalTechnologies(t *testing.T) {
+	aliases := map[string]string{"golang": "go", "js": "javascript"}
+	tests := []struct {
+		name         string
+		technologies []string
+		want         []string
+	}{
+		{"nothing listed", nil, []string{}},
+		{"names become slugs", []string{" Go ", "Ruby  on Rails"}, []string{"go", "ruby on rails"}},
+		{"aliases resolve to their tag", []string{"Golang", "JS"}, []string{"go", "javascript"}},
+		{"unknown names are kept", []string{"Zig"}, []string{"zig"}},
+		{"blank names are dropped", []string{"  ", "go"}, []string{"go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := canonicalTechnologies(test.technologies, aliases); !slices.Equal(got, test.want) {
+				t.Errorf("canonicalTechnologies = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// a solo listing "golang" fully matches a team tagged "Go"
+	score := matchScore("UTC", canonicalTechnologies([]string{"golang"}, aliases),
+		"UTC", canonicalTechnologies([]string{"Go"}, aliases))
+	if math.Abs(score-1) > 1e-9 {
+		t.Errorf("matchScore of golang and Go = %v, want 1", score)
+	}
+}
+
+func TestGroupSolos(t *testing.T) {
+	tests := []struct {
+		name          string
+		profiles      []database.DBSoloProfileInfo
+		groupSize     int
+		aliases       map[string]string
+		wantGroups    [][]string
+		wantLeftovers []string
+	}{
+		{
+			name:      "no profiles",
+			groupSize: 4,
+		},
+		{
+			name:          "single profile is left over",
+			profiles:      []database.DBSoloProfileInfo{soloProfile("a", "UTC")},
+			groupSize:     4,
+			wantLeftovers: []string{"a"},
+		},
+		{
+			name: "group size larger than the pool",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("a", "UTC"), soloProfile("b", "UTC"), soloProfile("c", "UTC"),
+			},
+			groupSize:  10,
+			wantGroups: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "pool divides evenly",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("a", "UTC"), soloProfile("b", "UTC"), soloProfile("c", "UTC"), soloProfile("d", "UTC"),
+			},
+			groupSize:  2,
+			wantGroups: [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			name: "last participant is left over",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("a", "UTC"), soloProfile("b", "UTC"), soloProfile("c", "UTC"),
+				soloProfile("d", "UTC"), soloProfile("e", "UTC"),
+			},
+			groupSize:     2,
+			wantGroups:    [][]string{{"a", "b"}, {"c", "d"}},
+			wantLeftovers: []string{"e"},
+		},
+		{
+			name: "best match joins the longest waiting participant",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("go-utc", "UTC", "Go"),
+				soloProfile("java-tokyo", "Asia/Tokyo", "Java"),
+				soloProfile("go-utc-2", "UTC", "Go"),
+				soloProfile("java-tokyo-2", "Asia/Tokyo", "Java"),
+			},
+			groupSize:  2,
+			wantGroups: [][]string{{"go-utc", "go-utc-2"}, {"java-tokyo", "java-tokyo-2"}},
+		},
+		{
+			name: "members are added by their average score against the group",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("a", "UTC", "Go"),
+				soloProfile("b", "UTC", "Go", "Rust"),
+				soloProfile("c", "Asia/Tokyo", "Rust"),
+				soloProfile("d", "UTC", "Rust"),
+			},
+			groupSize:  3,
+			wantGroups: [][]string{{"a", "b", "d"}},
+			// c ends up alone once the first group is full
+			wantLeftovers: []string{"c"},
+		},
+		{
+			name: "technologies are compared through tag aliases",
+			profiles: []database.DBSoloProfileInfo{
+				soloProfile("go", "UTC", "Go"),
+				soloProfile("java", "UTC", "Java"),
+				soloProfile("golang", "UTC", "Golang"),
+				soloProfile("java-2", "UTC", "Java"),
+			},
+			groupSize:  2,
+			aliases:    map[string]string{"golang": "go"},
+			wantGroups: [][]string{{"go", "golang"}, {"java", "java-2"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups, leftovers := groupSolos(test.profiles, test.groupSize, test.aliases)
+
+			if len(groups) != len(test.wantGroups) {
+				t.Fatalf("got %d groups, want %d", len(groups), len(test.wantGroups))
+			}
+			for i, group := range groups {
+				if got := profileNames(group); !slices.Equal(got, test.wantGroups[i]) {
+					t.Errorf("group %d = %v, want %v", i, got, test.wantGroups[i])
+				}
+			}
+			if got := profileNames(leftovers); !slices.Equal(got, test.wantLeftovers) {
+				t.Errorf("leftovers = %v, want %v", got, test.wantLeftovers)
+			}
+		})
+	}
+}
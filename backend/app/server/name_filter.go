@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bufio"
+	"codejam.io/config"
+	"codejam.io/server/models"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minTeamNameLength    = 3
+	maxTeamNameLength    = 40
+	minDisplayNameLength = 2
+	maxDisplayNameLength = 32
+	// punctuation allowed in names besides letters, digits and single spaces
+	namePunctuation = "-_.'&!"
+)
+
+var defaultReservedWords = []string{"admin", "administrator", "moderator", "organizer", "organiser", "staff", "official"}
+
+// NameFilter decides whether a user chosen name is acceptable.
+// Returns a message for the form when it isn't, "" otherwise.
+type NameFilter interface {
+	Check(name string) string
+}
+
+// WordListFilter rejects names containing blocked words, or reserved words that could pass the user off as staff.
+// Words are matched whole after undoing common letter substitutions, so "4dm1n" matches "admin" but "Scunthorpe"
+// doesn't match a blocked word it happens to contain.
+type WordListFilter struct {
+	blocked  map[string]bool
+	reserved map[string]bool
+}
+
+func NewWordListFilter(blocked []string, reserved []string) *WordListFilter {
+	filter := &WordListFilter{blocked: map[string]bool{}, reserved: map[string]bool{}}
+	for _, word := range blocked {
+		if word = normalizeWord(word); word != "" {
+			filter.blocked[word] = true
+		}
+	}
+	for _, word := range reserved {
+		if word = normalizeWord(word); word != "" {
+			filter.reserved[word] = true
+		}
+	}
+	return filter
+}
+
+// LoadWordListFilter builds the filter from the config, reading the blocked words file if there is one.
+func LoadWordListFilter(content config.ContentConfig) (*WordListFilter, error) {
+	blocked := append([]string{}, content.BlockedWords...)
+	if content.BlockedWordsFile != "" {
+		file, err := os.Open(content.BlockedWordsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				blocked = append(blocked, line)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	reserved := content.ReservedWords
+	if len(reserved) == 0 {
+		reserved = defaultReservedWords
+	}
+	return NewWordListFilter(blocked, reserved), nil
+}
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// normalizeWord lowercases the word, undoes letter substitutions and drops everything but letters.
+func normalizeWord(word string) string {
+	word = leetReplacer.Replace(strings.ToLower(word))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, word)
+}
+
+// nameWords splits a name into normalized words.  The whole name squashed together is included as well, which
+// catches words spelled out with separators such as "a.d.m.i.n".
+func nameWords(name string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '-' || r == '_' }) {
+		if word := normalizeWord(field); word != "" {
+			words = append(words, word)
+		}
+	}
+	if squashed := normalizeWord(name); squashed != "" {
+		words = append(words, squashed)
+	}
+	return words
+}
+
+func (filter *WordListFilter) Check(name string) string {
+	for _, word := range nameWords(name) {
+		if filter.blocked[word] {
+			return "contains a word that isn't allowed"
+		}
+		if filter.reserved[word] {
+			return fmt.Sprintf("can't contain %q", word)
+		}
+	}
+	return ""
+}
+
+// SetupNameFilter loads the word list filter used for team and display names.
+func (server *Server) SetupNameFilter() {
+	filter, err := LoadWordListFilter(server.Config.Content)
+	if err != nil {
+		logger.Critical("Error loading the name filter word list: %v", err)
+		os.Exit(1)
+	}
+	server.NameFilter = filter
+}
+
+// validateName applies the length, character and word list rules shared by team and display names.
+func (server *Server) validateName(field string, name string, minLength int, maxLength int,
+	response *models.FormResponse) {
+	if name == "" {
+		response.AddError(field, "required")
+		return
+	}
+
+	length := utf8.RuneCountInString(name)
+	if length < minLength || length > maxLength {
+		response.AddError(field, fmt.Sprintf("must be between %d and %d characters", minLength, maxLength))
+		return
+	}
+
+	first, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(first) && !unicode.IsDigit(first) {
+		response.AddError(field, "must start with a letter or number")
+		return
+	}
+	if strings.Contains(name, "  ") {
+		response.AddError(field, "can't contain repeated spaces")
+		return
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(namePunctuation, r) {
+			response.AddError(field, fmt.Sprintf("can only contain letters, numbers, spaces and %s", namePunctuation))
+			return
+		}
+	}
+
+	if server.NameFilter != nil {
+		if message := server.NameFilter.Check(name); message != "" {
+			response.AddError(field, message)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"codejam.io/config"
+	"codejam.io/server/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testNameFilter() *WordListFilter {
+	return NewWordListFilter([]string{"ass", "hell"}, defaultReservedWords)
+}
+
+func TestNormalizeWord(t *testing.T) {
+	tests := map[string]string{
+		"Admin":     "admin",
+		"4dm1n":     "admin",
+		"$t@ff":     "staff",
+		"A.D.M.I.N": "admin",
+		"0ff1c14l":  "official",
+		"Zoë":       "zoë",
+		"!!!":       "",
+	}
+	for word, want := range tests {
+		if got := normalizeWord(word); got != want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestWordListFilter(t *testing.T) {
+	filter := testNameFilter()
+	tests := []struct {
+		name    string
+		input   string
+		blocked bool
+		// reserved is the reserved word the name is rejected for, if any
+		reserved string
+	}{
+		{name: "clean name", input: "Gopher Gang"},
+		{name: "blocked word", input: "Hell Raisers", blocked: true},
+		{name: "blocked word in any case", input: "team HELL", blocked: true},
+		{name: "leet blocked word", input: "h3ll yeah", blocked: true},
+		{name: "blocked word with symbols", input: "4$$ kickers", blocked: true},
+		{name: "blocked word spelled out", input: "a.s.s", blocked: true},
+		{name: "blocked word split by separators", input: "h-e-l-l", blocked: true},
+		{name: "substring isn't blocked", input: "Classic Bass Players"},
+		{name: "substring at the start isn't blocked", input: "Hello Shell Scripts"},
+		{name: "scunthorpe", input: "Assembly Hackers"},
+		{name: "reserved word", input: "Admin", reserved: "admin"},
+		{name: "reserved word among others", input: "The Staff Team", reserved: "staff"},
+		{name: "leet reserved word", input: "4dm1n", reserved: "admin"},
+		{name: "reserved word with underscores", input: "official_team", reserved: "official"},
+		{name: "reserved substring isn't reserved", input: "Staffordshire Administrative"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := filter.Check(test.input)
+			switch {
+			case test.blocked:
+				if message != "contains a word that isn't allowed" {
+					t.Errorf("Check(%q) = %q, want the blocked word message", test.input, message)
+				}
+			case test.reserved != "":
+				if !strings.Contains(message, `"`+test.reserved+`"`) {
+					t.Errorf("Check(%q) = %q, want it rejected for %q", test.input, message, test.reserved)
+				}
+			default:
+				if message != "" {
+					t.Errorf("Check(%q) = %q, want it accepted", test.input, message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	server := &Server{NameFilter: testNameFilter()}
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+	}{
+		{name: "plain name", input: "Alice"},
+		{name: "allowed punctuation", input: "Tom & Jerry's Team-1!"},
+		{name: "letters outside ascii", input: "Zoë Ångström"},
+		{name: "shortest name", input: "Al"},
+		{name: "longest name", input: strings.Repeat("a", maxDisplayNameLength)},
+		{name: "empty", input: "", wantError: "required"},
+		{name: "too short", input: "A", wantError: "must be between"},
+		{name: "too long", input: strings.Repeat("a", maxDisplayNameLength+1), wantError: "must be between"},
+		{name: "length counts characters not bytes", input: strings.Repeat("ë", maxDisplayNameLength)},
+		{name: "starts with punctuation", input: "-Alice", wantError: "must start with a letter or number"},
+		{name: "starts with a space", input: " Alice", wantError: "must start with a letter or number"},
+		{name: "repeated spaces", input: "Alice  B", wantError: "repeated spaces"},
+		{name: "disallowed character", input: "Alice <3", wantError: "can only contain"},
+		{name: "emoji", input: "Alice 🎉", wantError: "can only contain"},
+		{name: "blocked word", input: "Hell Cat", wantError: "isn't allowed"},
+		{name: "reserved word", input: "Moderator Bob", wantError: `can't contain "moderator"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := models.NewFormResponse()
+			server.validateName("DisplayName", test.input, minDisplayNameLength, maxDisplayNameLength, &response)
+			if test.wantError == "" {
+				if len(response.Errors) > 0 {
+					t.Errorf("validateName(%q) = %v, want no errors", test.input, response.Errors)
+				}
+				return
+			}
+			if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Error, test.wantError) {
+				t.Errorf("validateName(%q) = %v, want an error containing %q", test.input, response.Errors, test.wantError)
+			} else if response.Errors[0].Field != "DisplayName" {
+				t.Errorf("error is for %q, want DisplayName", response.Errors[0].Field)
+			}
+		})
+	}
+}
+
+func TestValidateNameWithoutFilter(t *testing.T) {
+	response := models.NewFormResponse()
+	(&Server{}).validateName("Name", "Admin Team", minTeamNameLength, maxTeamNameLength, &response)
+	if len(response.Errors) > 0 {
+		t.Errorf("validateName without a NameFilter = %v, want no errors", response.Errors)
+	}
+}
+
+func TestSignUpDisplayName(t *testing.T) {
+	server := &Server{NameFilter: testNameFilter()}
+	tests := []struct {
+		serviceUserName string
+		want            string
+	}{
+		{"octocat", "octocat"},
+		{"  octocat  ", "octocat"},
+		{"admin", ""},
+		{"hell_spawn", ""},
+		{"x", ""},
+		{"user@example.com", ""},
+		{strings.Repeat("a", maxDisplayNameLength+1), ""},
+	}
+
+	for _, test := range tests {
+		got := server.signUpDisplayName(test.serviceUserName)
+		if test.want != "" {
+			if got != test.want {
+				t.Errorf("signUpDisplayName(%q) = %q, want %q", test.serviceUserName, got, test.want)
+			}
+			continue
+		}
+
+		// rejected names are replaced by a generated name that passes the checks itself
+		response := models.NewFormResponse()
+		server.validateDisplayName(got, &response)
+		if !strings.HasPrefix(got, generatedDisplayName+" ") || len(response.Errors) > 0 {
+			t.Errorf("signUpDisplayName(%q) = %q, want a valid generated name", test.serviceUserName, got)
+		}
+	}
+}
+
+func TestLoadWordListFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.txt")
+	if err := os.WriteFile(path, []byte("# comment\n\n  heck  \nd4rn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := LoadWordListFilter(config.ContentConfig{BlockedWords: []string{"ass"}, BlockedWordsFile: path})
+	if err != nil {
+		t.Fatalf("LoadWordListFilter error: %v", err)
+	}
+	for _, name := range []string{"heck yes", "darn it", "ass"} {
+		if filter.Check(name) == "" {
+			t.Errorf("Check(%q) accepted a blocked word", name)
+		}
+	}
+	if filter.Check("comment") != "" {
+		t.Error("comment lines were loaded as blocked words")
+	}
+	if filter.Check("Admin") == "" {
+		t.Error("default reserved words aren't used when none are configured")
+	}
+
+	filter, err = LoadWordListFilter(config.ContentConfig{ReservedWords: []string{"judge"}})
+	if err != nil {
+		t.Fatalf("LoadWordListFilter error: %v", err)
+	}
+	if filter.Check("Judge Dredd") == "" || filter.Check("Admin") != "" {
+		t.Error("configured reserved words don't replace the defaults")
+	}
+
+	if _, err = LoadWordListFilter(config.ContentConfig{BlockedWordsFile: path + ".missing"}); err == nil {
+		t.Error("LoadWordListFilter ignored a missing blocked words file")
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"codejam.io/database"
+	"errors"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+)
+
+func (server *Server) GetNotifications(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	notifications, err := database.GetNotifications(userId)
+	if err != nil {
+		logger.Error("GetNotifications error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, notifications)
+}
+
+func (server *Server) PutNotificationRead(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	notification, err := database.MarkNotificationRead(userId, convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			logger.Error("PutNotificationRead error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, notification)
+}
@@ -3,73 +3,362 @@ package server
 import (
 	"codejam.io/database"
 	"codejam.io/integrations"
+	"codejam.io/integrations/oidc"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
 	"github.com/emicklei/pgtalk/convert"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/oauth2"
 	githubOAuth "golang.org/x/oauth2/github"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 )
 
-// SetupOAuth initializes the OAuth provider specified in the application config.
+// OAuthProvider is a configured login provider.  Integration picks the integrations package that looks up the user,
+// OpenID Connect providers read the user from the ID token instead.
+type OAuthProvider struct {
+	Name        string
+	DisplayName string
+	Integration string
+	Config      *oauth2.Config
+	OIDC        *oidc.Provider
+}
+
+// OAuthProviderInfo is what the UI needs to render a login button for a provider.
+type OAuthProviderInfo struct {
+	Name        string
+	DisplayName string
+	LoginUrl    string
+	LinkUrl     string
+}
+
+// SetupOAuth initializes the OAuth providers specified in the application config.
 func (server *Server) SetupOAuth() {
-	var endpoint oauth2.Endpoint
-
-	switch strings.ToLower(server.Config.OAuth.Provider) {
-	case "github":
-		endpoint = githubOAuth.Endpoint
-	case "discord":
-		endpoint = oauth2.Endpoint{
-			AuthURL:  "https://discord.com/oauth2/authorize",
-			TokenURL: "https://discord.com/api/oauth2/token",
-		}
-	default:
-		logger.Critical("Invalid OAuth provider: %s", server.Config.OAuth.Provider)
+	if len(server.Config.OAuth) == 0 {
+		logger.Critical("No OAuth providers configured")
 		os.Exit(1)
 	}
 
-	server.OAuth = &oauth2.Config{
-		ClientID:     server.Config.OAuth.Id,
-		ClientSecret: server.Config.OAuth.Secret,
-		Endpoint:     endpoint,
-		RedirectURL:  server.Config.OAuth.RedirectUrl,
-		Scopes:       server.Config.OAuth.Scopes,
+	server.OAuthProviders = nil
+	for _, providerConfig := range server.Config.OAuth {
+		var endpoint oauth2.Endpoint
+		var displayName string
+		var oidcProvider *oidc.Provider
+		scopes := providerConfig.Scopes
+
+		integration := strings.ToLower(providerConfig.Provider)
+		switch integration {
+		case "github":
+			endpoint = githubOAuth.Endpoint
+			displayName = "GitHub"
+		case "discord":
+			endpoint = oauth2.Endpoint{
+				AuthURL:  "https://discord.com/oauth2/authorize",
+				TokenURL: "https://discord.com/api/oauth2/token",
+			}
+			displayName = "Discord"
+		case "oidc":
+			var err error
+			oidcProvider, err = oidc.Discover(providerConfig.Issuer, providerConfig.Id, oidc.ClaimMapping{
+				UserId:   providerConfig.UserIdClaim,
+				UserName: providerConfig.UserNameClaim,
+				Avatar:   providerConfig.AvatarClaim,
+			})
+			if err != nil {
+				logger.Critical("OpenID Connect discovery failed for %s: %v", providerConfig.Issuer, err)
+				os.Exit(1)
+			}
+			endpoint = oauth2.Endpoint{
+				AuthURL:  oidcProvider.AuthorizationEndpoint,
+				TokenURL: oidcProvider.TokenEndpoint,
+			}
+			displayName = "OpenID Connect"
+			if !slices.Contains(scopes, "openid") {
+				scopes = append([]string{"openid"}, scopes...)
+			}
+		default:
+			logger.Critical("Invalid OAuth provider: %s", providerConfig.Provider)
+			os.Exit(1)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(providerConfig.Name))
+		if name == "" {
+			name = integration
+		}
+		if server.findOAuthProvider(name) != nil {
+			logger.Critical("Duplicate OAuth provider name: %s", name)
+			os.Exit(1)
+		}
+		if providerConfig.DisplayName != "" {
+			displayName = providerConfig.DisplayName
+		}
+
+		server.OAuthProviders = append(server.OAuthProviders, &OAuthProvider{
+			Name:        name,
+			DisplayName: displayName,
+			Integration: integration,
+			Config: &oauth2.Config{
+				ClientID:     providerConfig.Id,
+				ClientSecret: providerConfig.Secret,
+				Endpoint:     endpoint,
+				RedirectURL:  providerConfig.RedirectUrl,
+				Scopes:       scopes,
+			},
+			OIDC: oidcProvider,
+		})
+		logger.Info("OAuth provider %s enabled", name)
 	}
 }
 
-func (server *Server) GetOAuthRedirect(ctx *gin.Context) {
-	url := server.OAuth.AuthCodeURL(ctx.Request.Header.Get("Referer"))
+func (server *Server) findOAuthProvider(name string) *OAuthProvider {
+	for _, provider := range server.OAuthProviders {
+		if provider.Name == name {
+			return provider
+		}
+	}
+	return nil
+}
+
+// getOAuthProvider returns the provider in the :provider route param, or the first configured one for the routes
+// without it.  Appropriate HTTP responses are set automatically.
+func (server *Server) getOAuthProvider(ctx *gin.Context) (*OAuthProvider, bool) {
+	name := ctx.Param("provider")
+	if name == "" {
+		return server.OAuthProviders[0], true
+	}
+	provider := server.findOAuthProvider(strings.ToLower(name))
+	if provider == nil {
+		ctx.Status(http.StatusNotFound)
+		return nil, false
+	}
+	return provider, true
+}
+
+// GetOAuthProviders lists the providers users can log in with, in config order.
+func (server *Server) GetOAuthProviders(ctx *gin.Context) {
+	providers := make([]OAuthProviderInfo, 0, len(server.OAuthProviders))
+	for _, provider := range server.OAuthProviders {
+		providers = append(providers, OAuthProviderInfo{
+			Name:        provider.Name,
+			DisplayName: provider.DisplayName,
+			LoginUrl:    "/oauth/" + provider.Name + "/redirect",
+			LinkUrl:     "/oauth/" + provider.Name + "/link",
+		})
+	}
+	ctx.JSON(http.StatusOK, providers)
+}
+
+// oauthFlow is the OAuth flow a browser has in progress with a provider, kept in its session between the redirect
+// to the provider and the callback.  State is the random value the callback must carry.  LinkUserId is set when the
+// flow links the provider account to that user instead of logging in with it.
+type oauthFlow struct {
+	State      string
+	Nonce      string
+	LinkUserId string
+	Redirect   string
+}
+
+func newOAuthState() (string, error) {
+	state := make([]byte, 32)
+	if _, err := rand.Read(state); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(state), nil
+}
+
+// oauthSessionKey namespaces a flow's session values by provider, so flows started with different providers in
+// the same browser don't overwrite each other.
+func oauthSessionKey(provider *OAuthProvider, field string) string {
+	return "oauth:" + provider.Name + ":" + field
+}
+
+func saveOAuthFlow(session sessions.Session, provider *OAuthProvider, flow oauthFlow) error {
+	session.Set(oauthSessionKey(provider, "state"), flow.State)
+	session.Set(oauthSessionKey(provider, "nonce"), flow.Nonce)
+	session.Set(oauthSessionKey(provider, "linkUserId"), flow.LinkUserId)
+	session.Set(oauthSessionKey(provider, "redirect"), flow.Redirect)
+	return session.Save()
+}
+
+// takeOAuthFlow removes the provider's flow from the session if the callback's state matches it, so each flow can
+// only be completed once.  Returns false if there is no such flow.
+func takeOAuthFlow(session sessions.Session, provider *OAuthProvider, state string) (oauthFlow, bool) {
+	var flow oauthFlow
+	flow.State, _ = session.Get(oauthSessionKey(provider, "state")).(string)
+	if flow.State == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return flow, false
+	}
+	flow.Nonce, _ = session.Get(oauthSessionKey(provider, "nonce")).(string)
+	flow.LinkUserId, _ = session.Get(oauthSessionKey(provider, "linkUserId")).(string)
+	flow.Redirect, _ = session.Get(oauthSessionKey(provider, "redirect")).(string)
+	for _, field := range []string{"state", "nonce", "linkUserId", "redirect"} {
+		session.Delete(oauthSessionKey(provider, field))
+	}
+	return flow, true
+}
+
+// startOAuthFlow redirects to the provider, remembering the flow in the session.  linkUserId is set to link the
+// provider account to that user, otherwise the flow logs in.
+func (server *Server) startOAuthFlow(ctx *gin.Context, provider *OAuthProvider, linkUserId string) {
+	state, err := newOAuthState()
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	flow := oauthFlow{
+		State:      state,
+		LinkUserId: linkUserId,
+		Redirect:   ctx.Request.Header.Get("Referer"),
+	}
+
+	var options []oauth2.AuthCodeOption
+	if provider.OIDC != nil {
+		if flow.Nonce, err = oidc.NewNonce(); err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		options = append(options, oauth2.SetAuthURLParam("nonce", flow.Nonce))
+	}
+
+	if err = saveOAuthFlow(sessions.Default(ctx), provider, flow); err != nil {
+		logger.Error("Error saving session: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	url := provider.Config.AuthCodeURL(state, options...)
 	ctx.Redirect(http.StatusFound, url)
 }
 
+func (server *Server) GetOAuthRedirect(ctx *gin.Context) {
+	provider, ok := server.getOAuthProvider(ctx)
+	if !ok {
+		return
+	}
+	server.startOAuthFlow(ctx, provider, "")
+}
+
+// GetOAuthLink starts the OAuth flow for adding another provider account to the session user, instead of logging
+// in with it.
+func (server *Server) GetOAuthLink(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	provider, ok := server.getOAuthProvider(ctx)
+	if !ok {
+		return
+	}
+	server.startOAuthFlow(ctx, provider, convert.UUIDToString(userId))
+}
+
+// linkOAuthIdentity adds the provider account to the user that started GetOAuthLink.  The outcome is reported as a
+// notification since the callback can only redirect.
+func linkOAuthIdentity(userId pgtype.UUID, identity database.DBUserIdentity) {
+	_, err := database.LinkUserIdentity(userId, identity)
+	if err != nil {
+		message := "Unable to link your account, please try again."
+		if database.IsUniqueViolation(err, database.UserIdentityConstraint) {
+			message = fmt.Sprintf("That %s account is already linked to a user.", identity.ServiceName)
+		} else {
+			logger.Error("linkOAuthIdentity: LinkUserIdentity error: %v", err)
+		}
+		_, _ = database.CreateNotification(userId, message, "/user")
+		return
+	}
+	_, _ = database.CreateNotification(userId,
+		fmt.Sprintf("Your %s account %s is now linked.", identity.ServiceName, identity.ServiceUserName), "/user")
+}
+
+// lookupOAuthUser gets the provider account that logged in.  OpenID Connect logins must carry the nonce that
+// startOAuthFlow stored with the flow.
+func lookupOAuthUser(provider *OAuthProvider, flow oauthFlow, token *oauth2.Token) *integrations.IntegrationUser {
+	if provider.OIDC == nil {
+		return integrations.GetUser(provider.Integration, token.AccessToken)
+	}
+
+	if flow.Nonce == "" {
+		logger.Error("OpenID Connect callback for %s without a login nonce", provider.Name)
+		return nil
+	}
+	idToken, _ := token.Extra("id_token").(string)
+	return integrations.GetOIDCUser(provider.OIDC, idToken, flow.Nonce)
+}
+
 func (server *Server) GetOAuthCallback(ctx *gin.Context) {
+	provider, ok := server.getOAuthProvider(ctx)
+	if !ok {
+		return
+	}
+
+	// the state ties the callback to a flow this browser started, anything else could be a forged login or link
+	session := sessions.Default(ctx)
+	flow, ok := takeOAuthFlow(session, provider, ctx.Query("state"))
+	if !ok {
+		logger.Error("OAuth callback for %s without a matching state", provider.Name)
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+	if err := session.Save(); err != nil {
+		logger.Error("Error saving session: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
 	authCode := ctx.Query("code")
-	redir := ctx.Query("state")
-	token, err := server.OAuth.Exchange(oauth2.NoContext, authCode)
+	token, err := provider.Config.Exchange(oauth2.NoContext, authCode)
 	if err != nil {
 		// todo - can any of these be handled?
 		logger.Error("OAuth exchange error: %v", err)
 		return
 	}
 
-	integrationName := strings.ToLower(server.Config.OAuth.Provider)
-	providerUser := integrations.GetUser(integrationName, token.AccessToken)
+	providerUser := lookupOAuthUser(provider, flow, token)
 	if providerUser != nil {
-		dbUser := database.CreateUser(integrationName, providerUser.UserId, providerUser.ServiceUserName, providerUser.AvatarUrl)
-		session := sessions.Default(ctx)
-		session.Set("userId", convert.UUIDToString(dbUser.Id))
-		session.Set("displayName", dbUser.DisplayName)
-		err = session.Save()
-		if err != nil {
-			logger.Error("Error saving session: %v", err)
+		identity := database.DBUserIdentity{
+			ServiceName:     provider.Name,
+			ServiceUserId:   providerUser.UserId,
+			ServiceUserName: providerUser.ServiceUserName,
+			AvatarUrl:       &providerUser.AvatarUrl,
+		}
+
+		if flow.LinkUserId != "" {
+			// the session user must still be the one who started linking
+			userId := server.OptionalSessionUserId(ctx)
+			if !userId.Valid || convert.UUIDToString(userId) != flow.LinkUserId {
+				ctx.Status(http.StatusUnauthorized)
+				return
+			}
+			linkOAuthIdentity(userId, identity)
+		} else {
+			dbUser, err := database.LoginWithIdentity(identity, server.signUpDisplayName(providerUser.ServiceUserName))
+			if err != nil {
+				// TODO error page
+				ctx.Status(http.StatusInternalServerError)
+				return
+			}
+			session.Set("userId", convert.UUIDToString(dbUser.Id))
+			session.Set("displayName", dbUser.DisplayName)
+			err = session.Save()
+			if err != nil {
+				logger.Error("Error saving session: %v", err)
+			}
 		}
 
+		redir := flow.Redirect
+		if redir == "" {
+			redir = "/"
+		}
 		ctx.Redirect(http.StatusFound, redir)
 	} else {
 		// TODO error page
-		logger.Error("Unable to lookup provider user for %s", integrationName)
+		logger.Error("Unable to lookup provider user for %s", provider.Name)
 	}
 }
 
@@ -78,7 +367,14 @@ func (server *Server) SetupOAuthRoutes() {
 
 	group := server.Gin.Group("/oauth")
 	{
+		group.GET("/providers", server.GetOAuthProviders)
+		group.GET("/:provider/redirect", server.GetOAuthRedirect)
+		group.GET("/:provider/callback", server.GetOAuthCallback)
+		group.GET("/:provider/link", server.GetOAuthLink)
+
+		// without a provider these use the first one, for redirect urls registered before there were several
 		group.GET("/redirect", server.GetOAuthRedirect)
 		group.GET("/callback", server.GetOAuthCallback)
+		group.GET("/link", server.GetOAuthLink)
 	}
 }
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"codejam.io/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// eventRosterLocked reports whether the event's policy no longer allows teams to be created, joined or left.
+// Rosters are open while the event is taking signups, and once it has started until the policy locks them.
+func eventRosterLocked(event database.DBEvent, statusCode string, now time.Time) bool {
+	switch statusCode {
+	case "SIGNUP":
+		return false
+	case "STARTED":
+		switch event.RosterLockPolicy {
+		case database.RosterLockNever:
+			return false
+		case database.RosterLockAfterHours:
+			if !event.StartsAt.Valid {
+				// without a start time there's nothing to count from, so lock like AT_START
+				return true
+			}
+			// starts_at has no timezone, times are stored in UTC
+			lockAt := event.StartsAt.Time.Add(time.Duration(event.RosterLockHours) * time.Hour)
+			return !now.UTC().Before(lockAt)
+		default:
+			return true
+		}
+	default:
+		return true
+	}
+}
+
+// teamRosterLocked is eventRosterLocked with the team's organizer override applied.  The override only matters
+// while the event is running, it can't reopen teams before signups or after the event.
+func teamRosterLocked(event database.DBEvent, statusCode string, team database.DBTeam, now time.Time) bool {
+	if !eventRosterLocked(event, statusCode, now) {
+		return false
+	}
+	return statusCode != "STARTED" || !team.RosterOpenUntil.Valid || !now.Before(team.RosterOpenUntil.Time)
+}
+
+// loadEventForRoster gets the event and its status code for the roster lock checks, logging failures.
+func loadEventForRoster(eventId pgtype.UUID) (database.DBEvent, string, bool) {
+	event, err := database.GetEvent(eventId)
+	if err != nil {
+		logger.Error("loadEventForRoster: GetEvent error: %v", err)
+		return event, "", false
+	}
+	statusCode, err := database.GetEventStatusCode(eventId)
+	if err != nil {
+		logger.Error("loadEventForRoster: GetEventStatusCode error: %v", err)
+		return event, "", false
+	}
+	return event, statusCode, true
+}
+
+// rosterLocked reports whether the team's membership can no longer be changed.  Errors count as locked.
+func (server *Server) rosterLocked(team database.DBTeam) bool {
+	event, statusCode, ok := loadEventForRoster(team.EventId)
+	return !ok || teamRosterLocked(event, statusCode, team, time.Now())
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"codejam.io/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+	"time"
+)
+
+func TestEventRosterLocked(t *testing.T) {
+	startsAt := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	lockAt := startsAt.Add(6 * time.Hour)
+	event := func(policy string) database.DBEvent {
+		return database.DBEvent{
+			StartsAt:         pgtype.Timestamp{Time: startsAt, Valid: true},
+			RosterLockPolicy: policy,
+			RosterLockHours:  6,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		event      database.DBEvent
+		statusCode string
+		now        time.Time
+		want       bool
+	}{
+		{"at start before the start", event(database.RosterLockAtStart), "SIGNUP", startsAt.Add(-time.Second), false},
+		{"at start once started", event(database.RosterLockAtStart), "STARTED", startsAt, true},
+		{"at start is the default", event(""), "STARTED", startsAt, true},
+		{"after hours just before the lock", event(database.RosterLockAfterHours), "STARTED",
+			lockAt.Add(-time.Second), false},
+		{"after hours at the lock", event(database.RosterLockAfterHours), "STARTED", lockAt, true},
+		{"after hours after the lock", event(database.RosterLockAfterHours), "STARTED", lockAt.Add(time.Second), true},
+		{"after hours compares in utc", event(database.RosterLockAfterHours), "STARTED",
+			lockAt.Add(-time.Second).In(time.FixedZone("UTC-5", -5*60*60)), false},
+		{"after hours without a start time", database.DBEvent{RosterLockPolicy: database.RosterLockAfterHours,
+			RosterLockHours: 6}, "STARTED", startsAt, true},
+		{"never at the start", event(database.RosterLockNever), "STARTED", startsAt, false},
+		{"never long after the start", event(database.RosterLockNever), "STARTED", lockAt.Add(24 * time.Hour), false},
+		{"signups are open", event(database.RosterLockNever), "SIGNUP", lockAt, false},
+		{"planning is locked", event(database.RosterLockNever), "PLANNING", startsAt.Add(-time.Hour), true},
+		{"ended is locked", event(database.RosterLockNever), "ENDED", lockAt.Add(24 * time.Hour), true},
+		{"ended after hours is locked", event(database.RosterLockAfterHours), "ENDED", lockAt.Add(-time.Second), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := eventRosterLocked(test.event, test.statusCode, test.now); got != test.want {
+				t.Errorf("eventRosterLocked = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTeamRosterLocked(t *testing.T) {
+	startsAt := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	openUntil := startsAt.Add(2 * time.Hour)
+	event := func(policy string) database.DBEvent {
+		return database.DBEvent{
+			StartsAt:         pgtype.Timestamp{Time: startsAt, Valid: true},
+			RosterLockPolicy: policy,
+			RosterLockHours:  6,
+		}
+	}
+	overridden := database.DBTeam{RosterOpenUntil: pgtype.Timestamptz{Time: openUntil, Valid: true}}
+
+	tests := []struct {
+		name       string
+		event      database.DBEvent
+		statusCode string
+		team       database.DBTeam
+		now        time.Time
+		want       bool
+	}{
+		{"no override once started", event(database.RosterLockAtStart), "STARTED", database.DBTeam{}, startsAt, true},
+		{"override before it runs out", event(database.RosterLockAtStart), "STARTED", overridden,
+			openUntil.Add(-time.Second), false},
+		{"override when it runs out", event(database.RosterLockAtStart), "STARTED", overridden, openUntil, true},
+		{"override after the policy would lock", event(database.RosterLockAfterHours), "STARTED",
+			database.DBTeam{RosterOpenUntil: pgtype.Timestamptz{Time: startsAt.Add(8 * time.Hour), Valid: true}},
+			startsAt.Add(7 * time.Hour), false},
+		{"open event ignores a past override", event(database.RosterLockNever), "STARTED", overridden,
+			openUntil.Add(time.Hour), false},
+		{"override doesn't unlock planning", event(database.RosterLockAtStart), "PLANNING", overridden,
+			startsAt.Add(-time.Hour), true},
+		{"override doesn't unlock the ended event", event(database.RosterLockAtStart), "ENDED", overridden,
+			openUntil.Add(-time.Second), true},
+		{"signups stay open without an override", event(database.RosterLockAtStart), "SIGNUP", database.DBTeam{},
+			startsAt.Add(-time.Hour), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := teamRosterLocked(test.event, test.statusCode, test.team, test.now); got != test.want {
+				t.Errorf("teamRosterLocked = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/redis"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/oauth2"
 	"os"
 )
 
@@ -15,9 +14,10 @@ var logger = logging.NewLogger(logging.Options{Name: "Server", Level: logging.DE
 var SessionCookieName string = "session"
 
 type Server struct {
-	Config config.Config
-	OAuth  *oauth2.Config
-	Gin    *gin.Engine
+	Config         config.Config
+	OAuthProviders []*OAuthProvider
+	Gin            *gin.Engine
+	NameFilter     NameFilter
 }
 
 func (server *Server) SetupSessionStore() {
@@ -50,13 +50,17 @@ func (server *Server) StartServer() {
 
 	server.SetupSessionStore()
 	server.SetupOAuth()
+	server.SetupNameFilter()
 
 	// Setup routes...
 	server.SetupOAuthRoutes()
 	server.SetupUserRoutes()
 	server.SetupEventRoutes()
 	server.SetupTeamRoutes()
+	server.SetupTagRoutes()
 	server.SetupAdminUserRoutes()
+	server.SetupAdminTeamRoutes()
+	server.SetupAdminTagRoutes()
 
 	server.SetupStaticRoutes()
 
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrz1836/go-sanitize"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	maxMatches           = 10
+	defaultAutoGroupSize = 4
+	// team name for auto-grouped solos whose owner's name doesn't make a valid team name
+	soloTeamFallbackName = "Solo Team"
+	maxProfileListItems  = 20
+)
+
+type PutSoloProfileRequest struct {
+	Skills       []string
+	Timezone     string
+	Availability string
+	Technologies []string
+}
+
+type TeamMatch struct {
+	Team  TeamListing
+	Score float64
+}
+
+type SoloMatch struct {
+	Profile database.DBSoloProfileInfo
+	Score   float64
+}
+
+type SoloMatchesResponse struct {
+	Teams []TeamMatch
+	Solos []SoloMatch
+}
+
+type AutoGroupResponse struct {
+	TeamIds   []pgtype.UUID
+	Ungrouped int
+}
+
+// sanitizeList trims and sanitizes a list of short values, dropping empty ones.  Never returns nil.
+func sanitizeList(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(sanitize.Scripts(value))
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func validateSoloProfile(profile database.DBSoloProfile, response *models.FormResponse) {
+	if profile.Timezone != "" && !validTimezone(profile.Timezone) {
+		response.AddError("Timezone", "must be an IANA timezone such as Europe/Berlin")
+	}
+	if len(profile.Skills) > maxProfileListItems {
+		response.AddError("Skills", fmt.Sprintf("at most %d skills", maxProfileListItems))
+	}
+	if len(profile.Technologies) > maxProfileListItems {
+		response.AddError("Technologies", fmt.Sprintf("at most %d technologies", maxProfileListItems))
+	}
+}
+
+func (server *Server) GetSoloProfile(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	profile, err := database.GetSoloProfile(userId, convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// PutSoloProfile creates or updates the session user's "looking for team" profile for the event.
+func (server *Server) PutSoloProfile(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request PutSoloProfileRequest
+	if !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	profile := database.DBSoloProfile{
+		UserId:       userId,
+		EventId:      convert.StringToUUID(ctx.Param("id")),
+		Skills:       sanitizeList(request.Skills),
+		Timezone:     strings.TrimSpace(request.Timezone),
+		Availability: strings.TrimSpace(sanitize.Scripts(request.Availability)),
+		Technologies: sanitizeList(request.Technologies),
+	}
+
+	response := models.NewFormResponse()
+	validateSoloProfile(profile, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	profile, err := database.SaveSoloProfile(profile)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	response.Data = profile
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) DeleteSoloProfile(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	_, err := database.DeleteSoloProfile(userId, convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetSoloMatches suggests recruiting teams and other solo participants for the session user, best matches first.
+func (server *Server) GetSoloMatches(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	eventId := convert.StringToUUID(ctx.Param("id"))
+
+	profile, err := database.GetSoloProfile(userId, eventId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "create a solo profile for this event first"})
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// every recruiting team is scored, not only the first page
+	lookingForMembers := true
+	var teams []database.DBTeamListing
+	for page := 1; ; page++ {
+		listings, total, err := database.SearchTeams(database.TeamSearch{
+			EventId:           eventId,
+			LookingForMembers: &lookingForMembers,
+			MinOpenSlots:      1,
+			Page:              page,
+			PageSize:          maxTeamPageSize,
+		})
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		teams = append(teams, listings...)
+		if len(listings) < maxTeamPageSize || len(teams) >= total {
+			break
+		}
+	}
+
+	solos, err := database.GetUnteamedSoloProfiles(eventId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	aliases, err := database.GetTagAliases()
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	technologies := canonicalTechnologies(profile.Technologies, aliases)
+
+	response := SoloMatchesResponse{Teams: []TeamMatch{}, Solos: []SoloMatch{}}
+	for _, team := range teams {
+		score := matchScore(profile.Timezone, technologies, team.Timezone,
+			canonicalTechnologies(team.Tags, aliases))
+		response.Teams = append(response.Teams, TeamMatch{Team: newTeamListing(team), Score: score})
+	}
+	for _, solo := range solos {
+		if solo.UserId == userId {
+			continue
+		}
+		score := matchScore(profile.Timezone, technologies, solo.Timezone,
+			canonicalTechnologies(solo.Technologies, aliases))
+		response.Solos = append(response.Solos, SoloMatch{Profile: solo, Score: score})
+	}
+
+	sort.SliceStable(response.Teams, func(i, j int) bool { return response.Teams[i].Score > response.Teams[j].Score })
+	sort.SliceStable(response.Solos, func(i, j int) bool { return response.Solos[i].Score > response.Solos[j].Score })
+	if len(response.Teams) > maxMatches {
+		response.Teams = response.Teams[:maxMatches]
+	}
+	if len(response.Solos) > maxMatches {
+		response.Solos = response.Solos[:maxMatches]
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// soloTeamName names a group's team after its owner, numbered from 2 on to find a free name.  A generic name is used
+// when the owner's name doesn't pass the same checks as names users pick, e.g. because it's too long.
+func (server *Server) soloTeamName(ownerName string, number int) string {
+	numbered := func(base string) string {
+		if number < 2 {
+			return base
+		}
+		return fmt.Sprintf("%s %d", base, number)
+	}
+
+	name := numbered(ownerName + "'s Team")
+	response := models.NewFormResponse()
+	server.validateName("Name", name, minTeamNameLength, maxTeamNameLength, &response)
+	if len(response.Errors) > 0 {
+		return numbered(soloTeamFallbackName)
+	}
+	return name
+}
+
+// createSoloTeam creates a private team for a group of solo participants, owned by the first of them.  The team,
+// its tags and its members are saved in one transaction so a failure doesn't leave a half built team.
+func (server *Server) createSoloTeam(event database.DBEvent, group []database.DBSoloProfileInfo) (database.DBTeam, error) {
+	team := database.DBTeam{
+		EventId:      event.Id,
+		Visibility:   database.TeamVisibilityPrivate,
+		Timezone:     group[0].Timezone,
+		Availability: group[0].Availability,
+		Description:  "Formed automatically from solo participants.",
+		WantedRoles:  []string{},
+	}
+
+	err := database.WithTransaction(func(tx pgx.Tx) error {
+		var teamId pgtype.UUID
+		tags, err := database.ResolveTagsTx(tx, group[0].Technologies)
+		if err != nil {
+			return err
+		}
+		team.Technologies = strings.Join(tagNames(tags), ", ")
+
+		// team names are unique per event, number the name until a free one is found
+		for number := 1; ; number++ {
+			team.Name = server.soloTeamName(group[0].DisplayName, number)
+			teamId, err = createTeamWithInviteCode(tx, team)
+			if !database.IsUniqueViolation(err, database.TeamNameConstraint) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		team.Id = teamId
+
+		if err = database.SetTeamTagsTx(tx, teamId, tags); err != nil {
+			return err
+		}
+		for i, member := range group {
+			role := database.TeamRoleMember
+			if i == 0 {
+				role = database.TeamRoleOwner
+			}
+			if _, err = database.AddTeamMemberTx(tx, member.UserId, teamId, role); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return team, err
+}
+
+// AutoGroupSolos lets an organizer put every remaining solo participant of the event into new private teams.
+// The longest waiting participant of each group becomes its owner.  The optional groupSize query parameter
+// defaults to 4 and is capped by the event's team size limit.
+func (server *Server) AutoGroupSolos(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	eventId := convert.StringToUUID(ctx.Param("id"))
+
+	isOrganizer, err := server.UserIsEventOrganizer(userId, eventId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	if !isOrganizer {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	response := models.NewFormResponse()
+	groupSize := parseIntQuery(ctx, "groupSize", 0, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	event, err := database.GetEvent(eventId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if groupSize <= 0 {
+		groupSize = defaultAutoGroupSize
+	}
+	if event.MaxTeamSize > 0 && groupSize > event.MaxTeamSize {
+		groupSize = event.MaxTeamSize
+	}
+	if groupSize < 2 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "groups need room for at least 2 members"})
+		return
+	}
+
+	solos, err := database.GetUnteamedSoloProfiles(eventId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	aliases, err := database.GetTagAliases()
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	groups, leftovers := groupSolos(solos, groupSize, aliases)
+	result := AutoGroupResponse{TeamIds: []pgtype.UUID{}, Ungrouped: len(leftovers)}
+	for _, group := range groups {
+		team, err := server.createSoloTeam(event, group)
+		if err != nil {
+			logger.Error("AutoGroupSolos: createSoloTeam error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		for _, member := range group {
+			_, _ = database.CreateNotification(member.UserId,
+				fmt.Sprintf("You've been placed in %s for %s.", team.Name, event.Title),
+				"/team/"+convert.UUIDToString(team.Id))
+		}
+		result.TeamIds = append(result.TeamIds, team.Id)
+	}
+
+	logger.Info("User %v auto-grouped %d solo participants into %d teams for event %v",
+		convert.UUIDToString(userId), len(solos)-len(leftovers), len(groups), convert.UUIDToString(eventId))
+	ctx.JSON(http.StatusOK, result)
+}
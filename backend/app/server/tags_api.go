@@ -0,0 +1,86 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTeamTags        = 20
+	maxTagLength       = 32
+	defaultTagSuggests = 10
+	maxTagSuggests     = 50
+	// punctuation allowed in tags besides letters, digits and spaces, for names like C++, C# and Node.js
+	tagPunctuation = "+#./-_"
+)
+
+// teamTagNames picks the tag names sent for a team, falling back to the comma separated Technologies that older
+// clients send.
+func teamTagNames(tags []string, technologies string) []string {
+	if len(tags) == 0 {
+		tags = strings.Split(technologies, ",")
+	}
+	return sanitizeList(tags)
+}
+
+func validateTags(names []string, response *models.FormResponse) {
+	if len(names) > maxTeamTags {
+		response.AddError("Tags", fmt.Sprintf("at most %d tags", maxTeamTags))
+		return
+	}
+	for _, name := range names {
+		if utf8.RuneCountInString(name) > maxTagLength {
+			response.AddError("Tags", fmt.Sprintf("tags must be at most %d characters", maxTagLength))
+			return
+		}
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(tagPunctuation, r) {
+				response.AddError("Tags", fmt.Sprintf("tags can only contain letters, numbers, spaces and %s",
+					tagPunctuation))
+				return
+			}
+		}
+	}
+}
+
+func tagNames(tags []database.DBTag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// GetTags suggests tags for autocomplete, most used first.  The prefix query parameter matches the start of tag
+// names and aliases, the optional limit defaults to 10.
+func (server *Server) GetTags(ctx *gin.Context) {
+	response := models.NewFormResponse()
+	limit := parseIntQuery(ctx, "limit", defaultTagSuggests, &response)
+	if limit < 1 || limit > maxTagSuggests {
+		response.AddError("limit", fmt.Sprintf("must be between 1 and %d", maxTagSuggests))
+	}
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	tags, err := database.SearchTags(ctx.Query("prefix"), limit)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []database.DBTagSuggestion{}
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+func (server *Server) SetupTagRoutes() {
+	server.Gin.GET("/tags", server.GetTags)
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInvitationHours = 72
+	maxInvitationHours     = 30 * 24
+)
+
+// PostTeamInvitationRequest addresses an invitation by UserId, or by DisplayName when UserId is empty.
+// ExpiresInHours defaults to 72.
+type PostTeamInvitationRequest struct {
+	UserId         string
+	DisplayName    string
+	ExpiresInHours int
+}
+
+// findInvitee resolves the user an invitation is addressed to, adding form errors when that isn't possible.
+func findInvitee(request PostTeamInvitationRequest, response *models.FormResponse) (database.DBUser, error) {
+	if request.UserId != "" {
+		user, err := database.GetUser(convert.StringToUUID(request.UserId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.AddError("UserId", "no such user")
+			return user, nil
+		}
+		return user, err
+	}
+
+	displayName := strings.TrimSpace(request.DisplayName)
+	if displayName == "" {
+		response.AddError("DisplayName", "required")
+		return database.DBUser{}, nil
+	}
+	users, err := database.GetUsersByDisplayName(displayName)
+	if err != nil {
+		return database.DBUser{}, err
+	}
+	switch len(users) {
+	case 0:
+		response.AddError("DisplayName", "no user has that display name")
+		return database.DBUser{}, nil
+	case 1:
+		return users[0], nil
+	default:
+		response.AddError("DisplayName", "several users have that display name, invite them by UserId")
+		return database.DBUser{}, nil
+	}
+}
+
+// PostTeamInvitation invites a specific user to the team.  They see it under their invitations and get a
+// notification.
+func (server *Server) PostTeamInvitation(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request PostTeamInvitationRequest
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok {
+		return
+	}
+	if _, ok = server.VerifyTeamPermission(ctx, team.Id, userId, PermissionManageMembers); !ok ||
+		!server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	if request.ExpiresInHours == 0 {
+		request.ExpiresInHours = defaultInvitationHours
+	}
+	if request.ExpiresInHours < 0 || request.ExpiresInHours > maxInvitationHours {
+		response.AddError("ExpiresInHours", fmt.Sprintf("must be between 1 and %d", maxInvitationHours))
+	}
+	invitee, err := findInvitee(request, &response)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if invitee.AccountStatus != database.AccountActive {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "that user can't join teams"})
+		return
+	}
+	isMember, err := database.IsTeamMember(team.Id, invitee.Id)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if isMember {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "already a member of this team"})
+		return
+	}
+	if !server.VerifyCanJoinEvent(ctx, invitee.Id, team.EventId) || !server.VerifyTeamHasRoom(ctx, team.Id) {
+		return
+	}
+
+	invitation, err := database.CreateTeamInvitation(database.DBTeamInvitation{
+		TeamId:          team.Id,
+		InviteeUserId:   invitee.Id,
+		InvitedByUserId: userId,
+		ExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().UTC().Add(time.Duration(request.ExpiresInHours) * time.Hour),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err, "idx_team_invitation_pending") {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "that user already has a pending invitation"})
+		} else {
+			logger.Error("PostTeamInvitation: CreateTeamInvitation error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	_, _ = database.CreateNotification(invitee.Id,
+		fmt.Sprintf("You've been invited to join %s.", team.Name), "/user/invitations")
+
+	response.Data = invitation
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GetTeamInvitations lists the team's pending invitations for members who can manage it.
+func (server *Server) GetTeamInvitations(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	teamId := convert.StringToUUID(ctx.Param("id"))
+	if _, ok = server.VerifyTeamPermission(ctx, teamId, userId, PermissionManageMembers); !ok {
+		return
+	}
+
+	invitations, err := database.GetTeamInvitations(teamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if invitations == nil {
+		invitations = []database.DBTeamInvitationInfo{}
+	}
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// DeleteTeamInvitation revokes an invitation.
+func (server *Server) DeleteTeamInvitation(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	teamId := convert.StringToUUID(ctx.Param("id"))
+	if _, ok = server.VerifyTeamPermission(ctx, teamId, userId, PermissionManageMembers); !ok {
+		return
+	}
+
+	_, err := database.DeleteTeamInvitation(teamId, convert.StringToUUID(ctx.Param("invitationId")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetMyInvitations lists the invitations addressed to the session user.  Only pending ones are listed unless the
+// all query parameter is true.
+func (server *Server) GetMyInvitations(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	invitations, err := database.GetUserInvitations(userId, ctx.Query("all") == "true")
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if invitations == nil {
+		invitations = []database.DBTeamInvitationInfo{}
+	}
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// getMyInvitation loads the invitation in the :id route param, which must be addressed to the user.
+// Appropriate HTTP responses are set automatically.
+func getMyInvitation(ctx *gin.Context, userId pgtype.UUID) (database.DBTeamInvitation, bool) {
+	invitation, err := database.GetTeamInvitation(convert.StringToUUID(ctx.Param("id")))
+	if err != nil || invitation.InviteeUserId != userId {
+		if err == nil || errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return invitation, false
+	}
+
+	if invitation.Status != database.InvitationPending || !invitation.ExpiresAt.Time.After(time.Now()) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "invitation is no longer pending"})
+		return invitation, false
+	}
+	return invitation, true
+}
+
+// notifyInvitationAnswered lets whoever sent the invitation know the invitee's answer and responds with it.
+func notifyInvitationAnswered(ctx *gin.Context, invitation database.DBTeamInvitation, team database.DBTeam) {
+	if invitation.InvitedByUserId.Valid {
+		inviteeName := "Someone"
+		if invitee, err := database.GetUser(invitation.InviteeUserId); err == nil {
+			inviteeName = invitee.DisplayName
+		}
+		_, _ = database.CreateNotification(invitation.InvitedByUserId,
+			fmt.Sprintf("%s %s your invitation to %s.", inviteeName, strings.ToLower(invitation.Status), team.Name),
+			"/team/"+convert.UUIDToString(team.Id))
+	}
+
+	ctx.JSON(http.StatusOK, invitation)
+}
+
+// AcceptInvitation adds the session user to the team they were invited to, as long as the team has room and they
+// aren't on another team in the event.
+func (server *Server) AcceptInvitation(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	invitation, ok := getMyInvitation(ctx, userId)
+	if !ok {
+		return
+	}
+
+	team, err := database.GetTeam(invitation.TeamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if server.rosterLocked(team) || team.Locked {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "the team's roster is locked"})
+		return
+	}
+
+	user, err := database.GetUser(userId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if user.AccountStatus != database.AccountActive {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	isMember, err := database.IsTeamMember(team.Id, userId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if !isMember {
+		if !server.VerifyCanJoinEvent(ctx, userId, team.EventId) || !server.VerifyTeamHasRoom(ctx, team.Id) {
+			return
+		}
+	}
+
+	invitation, err = database.AcceptTeamInvitation(invitation.Id, !isMember)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "invitation is no longer pending"})
+		} else {
+			logger.Error("AcceptInvitation error: %v", err)
+			addTeamMemberError(ctx, err)
+		}
+		return
+	}
+	notifyInvitationAnswered(ctx, invitation, team)
+}
+
+func (server *Server) DeclineInvitation(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	invitation, ok := getMyInvitation(ctx, userId)
+	if !ok {
+		return
+	}
+
+	team, err := database.GetTeam(invitation.TeamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	invitation, err = database.RespondToTeamInvitation(invitation.Id, database.InvitationDeclined)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "invitation is no longer pending"})
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	notifyInvitationAnswered(ctx, invitation, team)
+}
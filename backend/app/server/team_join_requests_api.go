@@ -0,0 +1,228 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/mrz1836/go-sanitize"
+	"net/http"
+	"strings"
+)
+
+const maxJoinRequestMessageLength = 500
+
+type PostJoinRequestRequest struct {
+	Message string
+}
+
+// PostJoinRequest asks the managers of a public team to let the session user join.  Private teams can only be
+// joined with an invite code.
+func (server *Server) PostJoinRequest(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request PostJoinRequestRequest
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	if team.Visibility != database.TeamVisibilityPublic {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "private teams can only be joined with an invite"})
+		return
+	}
+
+	user, err := database.GetUser(userId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if user.AccountStatus != database.AccountActive {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	isMember, err := database.IsTeamMember(team.Id, userId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if isMember {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "already a member of this team"})
+		return
+	}
+
+	if !server.VerifyCanJoinEvent(ctx, userId, team.EventId) || !server.VerifyTeamHasRoom(ctx, team.Id) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	request.Message = strings.TrimSpace(sanitize.Scripts(request.Message))
+	if len(request.Message) > maxJoinRequestMessageLength {
+		response.AddError("Message", fmt.Sprintf("must be at most %d characters", maxJoinRequestMessageLength))
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	joinRequest, err := database.CreateJoinRequest(team.Id, userId, request.Message)
+	if err != nil {
+		if database.IsUniqueViolation(err, "idx_team_join_request_pending") {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "a request to join this team is already pending"})
+		} else {
+			logger.Error("PostJoinRequest: CreateJoinRequest error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Data = joinRequest
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GetJoinRequests lists the pending join requests of a team for members who can manage it.
+func (server *Server) GetJoinRequests(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	teamId := convert.StringToUUID(ctx.Param("id"))
+	if _, ok := server.VerifyTeamPermission(ctx, teamId, userId, PermissionManageMembers); !ok {
+		return
+	}
+
+	requests, err := database.GetPendingJoinRequests(teamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// ApproveJoinRequest adds the requester to the team and lets them know.
+func (server *Server) ApproveJoinRequest(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok {
+		return
+	}
+	if _, ok = server.VerifyTeamPermission(ctx, team.Id, userId, PermissionManageMembers); !ok {
+		return
+	}
+
+	requestId := convert.StringToUUID(ctx.Param("requestId"))
+	joinRequest, err := database.GetJoinRequest(team.Id, requestId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	if joinRequest.Status != database.JoinRequestPending {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "join request was already decided"})
+		return
+	}
+
+	// the requester may have been banned since they asked to join
+	requester, err := database.GetUser(joinRequest.UserId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if requester.AccountStatus != database.AccountActive {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "that user can't join teams"})
+		return
+	}
+
+	isMember, err := database.IsTeamMember(team.Id, joinRequest.UserId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if !isMember {
+		if !server.VerifyCanJoinEvent(ctx, joinRequest.UserId, team.EventId) ||
+			!server.VerifyTeamHasRoom(ctx, team.Id) {
+			return
+		}
+	}
+
+	joinRequest, err = database.ApproveJoinRequest(team.Id, joinRequest.Id, userId, !isMember)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "join request was already decided"})
+		} else {
+			logger.Error("ApproveJoinRequest error: %v", err)
+			addTeamMemberError(ctx, err)
+		}
+		return
+	}
+	notifyJoinRequestDecided(ctx, team, joinRequest)
+}
+
+// DeclineJoinRequest rejects the request and lets the requester know.
+func (server *Server) DeclineJoinRequest(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	teamId := convert.StringToUUID(ctx.Param("id"))
+	if _, ok := server.VerifyTeamPermission(ctx, teamId, userId, PermissionManageMembers); !ok {
+		return
+	}
+
+	team, err := database.GetTeam(teamId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	joinRequest, err := database.GetJoinRequest(team.Id, convert.StringToUUID(ctx.Param("requestId")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	server.declineJoinRequest(ctx, team, joinRequest)
+}
+
+// declineJoinRequest records that the request was declined and notifies the requester.
+func (server *Server) declineJoinRequest(ctx *gin.Context, team database.DBTeam, joinRequest database.DBJoinRequest) {
+	deciderId, _ := server.GetSessionUserId(ctx)
+	joinRequest, err := database.DecideJoinRequest(team.Id, joinRequest.Id, database.JoinRequestDeclined, deciderId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "join request was already decided"})
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	notifyJoinRequestDecided(ctx, team, joinRequest)
+}
+
+// notifyJoinRequestDecided lets the requester know the outcome of their request and responds with it.
+func notifyJoinRequestDecided(ctx *gin.Context, team database.DBTeam, joinRequest database.DBJoinRequest) {
+	var message string
+	if joinRequest.Status == database.JoinRequestApproved {
+		message = fmt.Sprintf("Your request to join %s was approved!", team.Name)
+	} else {
+		message = fmt.Sprintf("Your request to join %s was declined.", team.Name)
+	}
+	// the decision stands even if the notification fails, it's logged by CreateNotification
+	_, _ = database.CreateNotification(joinRequest.UserId, message, "/team/"+convert.UUIDToString(team.Id))
+
+	ctx.JSON(http.StatusOK, joinRequest)
+}
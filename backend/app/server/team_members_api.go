@@ -0,0 +1,346 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+)
+
+type LeaveTeamResponse struct {
+	Disbanded bool
+}
+
+type TransferOwnershipRequest struct {
+	UserId string
+}
+
+type TeamRoleRequest struct {
+	Role string
+}
+
+// TeamPermission is something a team role allows its members to do
+type TeamPermission string
+
+const (
+	PermissionEditTeam      TeamPermission = "edit team"
+	PermissionManageMembers TeamPermission = "manage members"
+)
+
+// teamRolePermissions lists what each role may do.  Only the owner can change roles and transfer ownership,
+// which isn't a permission that can be handed out.
+var teamRolePermissions = map[string][]TeamPermission{
+	database.TeamRoleOwner:   {PermissionEditTeam, PermissionManageMembers},
+	database.TeamRoleCoOwner: {PermissionEditTeam, PermissionManageMembers},
+	database.TeamRoleMember:  {},
+}
+
+// teamRoleHasPermission reports whether the role grants the permission, unknown roles grant nothing.
+func teamRoleHasPermission(teamRole string, permission TeamPermission) bool {
+	for _, granted := range teamRolePermissions[teamRole] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTeamForUpdate loads the team in the :id route param and verifies its roster can still be changed, which
+// isn't the case once the event's rosters lock or an organizer locks the team.
+// Appropriate HTTP responses are set automatically.
+// Returns the team and true if it may be modified, false otherwise.
+func (server *Server) GetTeamForUpdate(ctx *gin.Context) (database.DBTeam, bool) {
+	team, err := database.GetTeam(convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return team, false
+	}
+
+	if server.rosterLocked(team) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "team rosters are locked for this event"})
+		return team, false
+	}
+
+	if team.Locked {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "team is locked by an organizer"})
+		return team, false
+	}
+
+	return team, true
+}
+
+// VerifyCanJoinEvent checks the user isn't already on a team in the event, unless the event allows multiple teams.
+// Appropriate HTTP responses are set automatically.
+// Returns true if the user may join a team, false otherwise.
+func (server *Server) VerifyCanJoinEvent(ctx *gin.Context, userId pgtype.UUID, eventId pgtype.UUID) bool {
+	allowed, err := database.CanJoinEventTeam(userId, eventId)
+	if err != nil {
+		logger.Error("VerifyCanJoinEvent: CanJoinEventTeam error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "already on a team for this event"})
+		return false
+	}
+	return true
+}
+
+// VerifyTeamHasRoom checks the team is below the event's team size limit.
+// Appropriate HTTP responses are set automatically.
+// Returns true if someone else can join, false otherwise.
+func (server *Server) VerifyTeamHasRoom(ctx *gin.Context, teamId pgtype.UUID) bool {
+	hasRoom, err := database.TeamHasRoom(teamId)
+	if err != nil {
+		logger.Error("VerifyTeamHasRoom: TeamHasRoom error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return false
+	}
+	if !hasRoom {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "team is full"})
+		return false
+	}
+	return true
+}
+
+// addTeamMemberError sets the response for an AddTeamMember failure, the one team per event and team size rules
+// may still be hit when users join at the same time.
+func addTeamMemberError(ctx *gin.Context, err error) {
+	if database.IsUniqueViolation(err, database.OneTeamPerEventConstraint) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "already on a team for this event"})
+	} else if database.IsCheckViolation(err, database.TeamSizeConstraint) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "team is full"})
+	} else {
+		ctx.Status(http.StatusInternalServerError)
+	}
+}
+
+// VerifyTeamPermission checks that the user is on the team with a role that has the permission.
+// Appropriate HTTP responses are set automatically.
+// Returns the user's membership and true if they have the permission, false otherwise.
+func (server *Server) VerifyTeamPermission(ctx *gin.Context, teamId pgtype.UUID, userId pgtype.UUID,
+	permission TeamPermission) (database.DBTeamMember, bool) {
+	member, err := database.GetTeamMember(teamId, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusForbidden)
+		} else {
+			logger.Error("VerifyTeamPermission: GetTeamMember error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return member, false
+	}
+
+	if !teamRoleHasPermission(member.TeamRole, permission) {
+		ctx.Status(http.StatusForbidden)
+		return member, false
+	}
+
+	return member, true
+}
+
+// VerifyTeamOwner checks that the user is the owner of the team.
+// Appropriate HTTP responses are set automatically.
+// Returns true if the user owns the team, false otherwise.
+func (server *Server) VerifyTeamOwner(ctx *gin.Context, teamId pgtype.UUID, userId pgtype.UUID) bool {
+	member, err := database.GetTeamMember(teamId, userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusForbidden)
+		} else {
+			logger.Error("VerifyTeamOwner: GetTeamMember error: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	if member.TeamRole != database.TeamRoleOwner {
+		ctx.Status(http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// LeaveTeam removes the session user from the team.  The owner must hand over ownership before leaving
+// unless they're the last member, in which case the team is disbanded.
+func (server *Server) LeaveTeam(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok {
+		return
+	}
+
+	leave, err := database.LeaveTeam(team.Id, userId, true)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else if errors.Is(err, database.ErrOwnerMustTransfer) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "transfer ownership before leaving the team"})
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	if leave.Disbanded {
+		logger.Info("Team %v disbanded after its last member left", convert.UUIDToString(team.Id))
+	}
+
+	ctx.JSON(http.StatusOK, LeaveTeamResponse{Disbanded: leave.Disbanded})
+}
+
+// RemoveMember lets members who can manage the team remove another member.  Co-owners can only remove plain
+// members, and the owner can't be removed at all.
+func (server *Server) RemoveMember(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok {
+		return
+	}
+	manager, ok := server.VerifyTeamPermission(ctx, team.Id, userId, PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	memberId := convert.StringToUUID(ctx.Param("userId"))
+	if memberId == userId {
+		// members leave through LeaveTeam so the ownership rules apply
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	// the owner can't be removed, and co-owners can only remove plain members
+	removableRoles := []string{database.TeamRoleMember}
+	if manager.TeamRole == database.TeamRoleOwner {
+		removableRoles = append(removableRoles, database.TeamRoleCoOwner)
+	}
+	_, err := database.RemoveTeamMember(team.Id, memberId, removableRoles)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else if errors.Is(err, database.ErrTeamRoleProtected) {
+			ctx.Status(http.StatusForbidden)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	teamResponse, err := buildTeamResponse(team, userId)
+	if err != nil {
+		logger.Error("RemoveMember: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, teamResponse)
+}
+
+// TransferOwnership hands the owner role to another member of the team.  The current owner becomes a member.
+func (server *Server) TransferOwnership(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request TransferOwnershipRequest
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok ||
+		!server.VerifyTeamOwner(ctx, team.Id, userId) ||
+		!server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	newOwnerId := convert.StringToUUID(request.UserId)
+	if newOwnerId == userId {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	_, err := database.TransferTeamOwnership(team.Id, userId, newOwnerId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "new owner must be a member of the team"})
+		} else if errors.Is(err, database.ErrOwnershipNotTransferred) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "only the owner can transfer ownership to a member"})
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	teamResponse, err := buildTeamResponse(team, userId)
+	if err != nil {
+		logger.Error("TransferOwnership: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("User %v transferred ownership of team %v to %v", convert.UUIDToString(userId),
+		convert.UUIDToString(team.Id), request.UserId)
+	ctx.JSON(http.StatusOK, teamResponse)
+}
+
+// ChangeMemberRole lets the owner make another member a co-owner or a plain member.  Ownership itself is handed
+// over with TransferOwnership.
+func (server *Server) ChangeMemberRole(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+	var request TeamRoleRequest
+	team, ok := server.GetTeamForUpdate(ctx)
+	if !ok ||
+		!server.VerifyTeamOwner(ctx, team.Id, userId) ||
+		!server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	if request.Role != database.TeamRoleCoOwner && request.Role != database.TeamRoleMember {
+		response.AddError("Role", fmt.Sprintf("must be %s or %s", database.TeamRoleCoOwner, database.TeamRoleMember))
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	memberId := convert.StringToUUID(ctx.Param("userId"))
+	if memberId == userId {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "transfer ownership to give up the owner role"})
+		return
+	}
+
+	_, err := database.SetTeamMemberRole(team.Id, memberId, request.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	_, _ = database.CreateNotification(memberId,
+		fmt.Sprintf("You are now a %s of %s.", request.Role, team.Name),
+		"/team/"+convert.UUIDToString(team.Id))
+
+	teamResponse, err := buildTeamResponse(team, userId)
+	if err != nil {
+		logger.Error("ChangeMemberRole: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("User %v made %v a %s of team %v", convert.UUIDToString(userId), ctx.Param("userId"),
+		request.Role, convert.UUIDToString(team.Id))
+	ctx.JSON(http.StatusOK, teamResponse)
+}
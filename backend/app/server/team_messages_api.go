@@ -0,0 +1,297 @@
+package server
+
+import (
+	"codejam.io/database"
+	"codejam.io/server/models"
+	"errors"
+	"fmt"
+	"github.com/emicklei/pgtalk/convert"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxTeamMessageLength       = 4000
+	defaultTeamMessagePageSize = 25
+	maxTeamMessagePageSize     = 100
+)
+
+type TeamMessageRequest struct {
+	Body string
+}
+
+type PinTeamMessageRequest struct {
+	Pinned bool
+}
+
+// TeamMessage is a message board post along with its rendered markdown
+type TeamMessage struct {
+	Id          pgtype.UUID
+	UserId      pgtype.UUID
+	DisplayName *string
+	Body        string
+	Html        string
+	Pinned      bool
+	EditedOn    pgtype.Timestamptz
+	CreatedOn   pgtype.Timestamptz
+}
+
+type TeamMessagesResponse struct {
+	Messages []TeamMessage
+	Page     int
+	PageSize int
+	Total    int
+}
+
+// teamBoardViewer is who is looking at a team's message board
+type teamBoardViewer struct {
+	team        database.DBTeam
+	userId      pgtype.UUID
+	teamRole    string // empty when the viewer isn't on the team
+	isOrganizer bool
+}
+
+func newTeamMessage(message database.DBTeamMessage, displayName *string) TeamMessage {
+	return TeamMessage{
+		Id:          message.Id,
+		UserId:      message.UserId,
+		DisplayName: displayName,
+		Body:        message.Body,
+		Html:        renderMarkdown(message.Body),
+		Pinned:      message.Pinned,
+		EditedOn:    message.EditedOn,
+		CreatedOn:   message.CreatedOn,
+	}
+}
+
+// authorDisplayName looks up the display name shown on a message, nil if the author's account is gone
+func authorDisplayName(userId pgtype.UUID) *string {
+	if !userId.Valid {
+		return nil
+	}
+	user, err := database.GetUser(userId)
+	if err != nil {
+		return nil
+	}
+	return &user.DisplayName
+}
+
+func validateTeamMessage(body string, response *models.FormResponse) {
+	if body == "" {
+		response.AddError("Body", "required")
+	} else if len(body) > maxTeamMessageLength {
+		response.AddError("Body", fmt.Sprintf("must be at most %d characters", maxTeamMessageLength))
+	}
+}
+
+// VerifyTeamBoardAccess checks the session user is on the team in the :id route param or organizes its event, the
+// message board is private to them.
+// Appropriate HTTP responses are set automatically.
+// Returns the viewer and true if they can see the board, false otherwise.
+func (server *Server) VerifyTeamBoardAccess(ctx *gin.Context) (teamBoardViewer, bool) {
+	var viewer teamBoardViewer
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return viewer, false
+	}
+	viewer.userId = userId
+
+	team, err := database.GetTeam(convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return viewer, false
+	}
+	viewer.team = team
+
+	member, err := database.GetTeamMember(team.Id, userId)
+	if err == nil {
+		viewer.teamRole = member.TeamRole
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		ctx.Status(http.StatusInternalServerError)
+		return viewer, false
+	}
+
+	viewer.isOrganizer, err = server.UserIsEventOrganizer(userId, team.EventId)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return viewer, false
+	}
+
+	if viewer.teamRole == "" && !viewer.isOrganizer {
+		ctx.Status(http.StatusForbidden)
+		return viewer, false
+	}
+	return viewer, true
+}
+
+// getTeamMessage loads the message in the :messageId route param.
+// Appropriate HTTP responses are set automatically.
+func getTeamMessage(ctx *gin.Context, teamId pgtype.UUID) (database.DBTeamMessage, bool) {
+	message, err := database.GetTeamMessage(teamId, convert.StringToUUID(ctx.Param("messageId")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return message, false
+	}
+	return message, true
+}
+
+func (server *Server) GetTeamMessages(ctx *gin.Context) {
+	viewer, ok := server.VerifyTeamBoardAccess(ctx)
+	if !ok {
+		return
+	}
+
+	response := models.NewFormResponse()
+	page := parseIntQuery(ctx, "page", 1, &response)
+	pageSize := parseIntQuery(ctx, "pageSize", defaultTeamMessagePageSize, &response)
+	if page < 1 {
+		response.AddError("page", "must be at least 1")
+	}
+	if pageSize < 1 || pageSize > maxTeamMessagePageSize {
+		response.AddError("pageSize", fmt.Sprintf("must be between 1 and %d", maxTeamMessagePageSize))
+	}
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	dbMessages, total, err := database.GetTeamMessages(viewer.team.Id, page, pageSize)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	messages := make([]TeamMessage, 0, len(dbMessages))
+	for _, message := range dbMessages {
+		messages = append(messages, newTeamMessage(message.DBTeamMessage, message.DisplayName))
+	}
+
+	ctx.JSON(http.StatusOK, TeamMessagesResponse{
+		Messages: messages,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+func (server *Server) PostTeamMessage(ctx *gin.Context) {
+	var request TeamMessageRequest
+	viewer, ok := server.VerifyTeamBoardAccess(ctx)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	body := strings.TrimSpace(request.Body)
+	validateTeamMessage(body, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	message, err := database.CreateTeamMessage(viewer.team.Id, viewer.userId, body)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	response.Data = newTeamMessage(message, authorDisplayName(message.UserId))
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// PutTeamMessage edits a message, only its author can do that.
+func (server *Server) PutTeamMessage(ctx *gin.Context) {
+	var request TeamMessageRequest
+	viewer, ok := server.VerifyTeamBoardAccess(ctx)
+	if !ok {
+		return
+	}
+	message, ok := getTeamMessage(ctx, viewer.team.Id)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	if message.UserId != viewer.userId {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	response := models.NewFormResponse()
+	body := strings.TrimSpace(request.Body)
+	validateTeamMessage(body, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	message, err := database.UpdateTeamMessage(viewer.team.Id, message.Id, body)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	response.Data = newTeamMessage(message, authorDisplayName(message.UserId))
+	ctx.JSON(http.StatusOK, response)
+}
+
+// DeleteTeamMessage removes a message.  Authors can delete their own messages, members who manage the team and
+// organizers can delete any message.
+func (server *Server) DeleteTeamMessage(ctx *gin.Context) {
+	viewer, ok := server.VerifyTeamBoardAccess(ctx)
+	if !ok {
+		return
+	}
+	message, ok := getTeamMessage(ctx, viewer.team.Id)
+	if !ok {
+		return
+	}
+
+	if message.UserId != viewer.userId && !teamRoleHasPermission(viewer.teamRole, PermissionManageMembers) &&
+		!viewer.isOrganizer {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	_, err := database.DeleteTeamMessage(viewer.team.Id, message.Id)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// PutTeamMessagePin pins or unpins a message, which members who can edit the team and organizers can do.
+func (server *Server) PutTeamMessagePin(ctx *gin.Context) {
+	var request PinTeamMessageRequest
+	viewer, ok := server.VerifyTeamBoardAccess(ctx)
+	if !ok {
+		return
+	}
+	message, ok := getTeamMessage(ctx, viewer.team.Id)
+	if !ok || !server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	if !teamRoleHasPermission(viewer.teamRole, PermissionEditTeam) && !viewer.isOrganizer {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	message, err := database.SetTeamMessagePinned(viewer.team.Id, message.Id, request.Pinned)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.JSON(http.StatusOK, newTeamMessage(message, authorDisplayName(message.UserId)))
+}
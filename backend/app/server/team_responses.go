@@ -0,0 +1,128 @@
+package server
+
+import (
+	"codejam.io/database"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TeamInfo is the client view of a team.  The invite fields are only filled in for members of the team, so invite
+// codes of private teams can't leak through public endpoints.
+type TeamInfo struct {
+	Id                pgtype.UUID
+	EventId           pgtype.UUID
+	Name              string
+	Visibility        string
+	Timezone          string
+	Technologies      string
+	Tags              []string
+	Availability      string
+	Description       string
+	LookingForMembers bool
+	WantedRoles       []string
+	Locked            bool
+	InviteCode        string              `json:",omitempty"`
+	InviteExpiresAt   *pgtype.Timestamptz `json:",omitempty"`
+	InviteMaxUses     *int                `json:",omitempty"`
+	InviteUses        *int                `json:",omitempty"`
+}
+
+// TeamMemberInfo is the client view of a team member, without account details like ServiceUserId and AccountStatus
+type TeamMemberInfo struct {
+	Id          pgtype.UUID
+	DisplayName string
+	AvatarUrl   *string
+	TeamRole    string
+}
+
+// TeamListing is a team in the search results
+type TeamListing struct {
+	TeamInfo
+	MemberCount int
+	OpenSlots   int
+}
+
+type GetTeamResponse struct {
+	Team    *TeamInfo
+	Event   *database.DBEvent
+	Members *[]TeamMemberInfo // array(slice) of a struct
+	Overlap *TeamOverlap      `json:",omitempty"` // only shown to members, it reveals their timezones
+
+	isMember bool // whether the viewer is on the team
+}
+
+func newTeamInfo(team database.DBTeam, isMember bool) TeamInfo {
+	info := TeamInfo{
+		Id:                team.Id,
+		EventId:           team.EventId,
+		Name:              team.Name,
+		Visibility:        team.Visibility,
+		Timezone:          team.Timezone,
+		Technologies:      team.Technologies,
+		Availability:      team.Availability,
+		Description:       team.Description,
+		LookingForMembers: team.LookingForMembers,
+		WantedRoles:       team.WantedRoles,
+		Locked:            team.Locked,
+	}
+	if isMember {
+		info.InviteCode = team.InviteCode
+		info.InviteMaxUses = team.InviteMaxUses
+		info.InviteUses = &team.InviteUses
+		if team.InviteExpiresAt.Valid {
+			info.InviteExpiresAt = &team.InviteExpiresAt
+		}
+	}
+	return info
+}
+
+func newTeamListing(listing database.DBTeamListing) TeamListing {
+	teamInfo := newTeamInfo(listing.DBTeam, false)
+	teamInfo.Tags = listing.Tags
+	return TeamListing{
+		TeamInfo:    teamInfo,
+		MemberCount: listing.MemberCount,
+		OpenSlots:   listing.OpenSlots,
+	}
+}
+
+// buildTeamResponse gathers the event and members of a team into a GetTeamResponse as seen by the viewer.
+// viewerId may be invalid for anonymous viewers.
+func buildTeamResponse(team database.DBTeam, viewerId pgtype.UUID) (GetTeamResponse, error) {
+	var teamResponse GetTeamResponse
+
+	event, err := database.GetEvent(team.EventId)
+	if err != nil {
+		return teamResponse, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	dbMembers, err := database.GetMembersByTeamId(team.Id)
+	if err != nil {
+		return teamResponse, fmt.Errorf("failed to get members: %w", err)
+	}
+
+	members := make([]TeamMemberInfo, 0, len(*dbMembers))
+	for _, member := range *dbMembers {
+		if viewerId.Valid && member.Id == viewerId {
+			teamResponse.isMember = true
+		}
+		members = append(members, TeamMemberInfo{
+			Id:          member.Id,
+			DisplayName: member.DisplayName,
+			AvatarUrl:   member.AvatarUrl,
+			TeamRole:    member.TeamRole,
+		})
+	}
+
+	tags, err := database.GetTeamTags(team.Id)
+	if err != nil {
+		return teamResponse, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	teamInfo := newTeamInfo(team, teamResponse.isMember)
+	teamInfo.Tags = tagNames(tags)
+	teamResponse.Team = &teamInfo
+	teamResponse.Event = &event
+	teamResponse.Members = &members
+	return teamResponse, nil
+}
@@ -1,70 +1,290 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"codejam.io/database"
+	"codejam.io/server/models"
 	"github.com/emicklei/pgtalk/convert"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrz1836/go-sanitize"
 
-	"crypto/md5"
 	"crypto/rand"
-	"encoding/hex"
-	"math"
-	"math/big"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type CreateTeamRequest struct {
 	// sent from UI, to be processed in the server into a DBTeam structure
 	// referenced in server/teams.go/CreateTeam line 53
-	EventId      string
-	Name         string
-	Visibility   string
-	Availability string
-	Description  string
-	Technologies string
-	Timezone     string
+	EventId           string
+	Name              string
+	Visibility        string
+	Availability      string
+	Description       string
+	Technologies      string // comma separated, only used when Tags is empty
+	Tags              []string
+	Timezone          string
+	LookingForMembers bool
+	WantedRoles       []string
 }
 
-type GetTeamResponse struct {
-	Team    *database.DBTeam
-	Event   *database.DBEvent
-	Members *[]database.DBTeamMemberInfo // array(slice) of a struct
+// UpdateTeamRequest holds the team fields a team owner is allowed to edit
+type UpdateTeamRequest struct {
+	Name              string
+	Visibility        string
+	Availability      string
+	Description       string
+	Technologies      string // comma separated, only used when Tags is empty
+	Tags              []string
+	Timezone          string
+	LookingForMembers bool
+	WantedRoles       []string
 }
 
-func MD5HashCode(teamName string) (string, error) {
-	randNum, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		return "Md5 hash error", err
+type SearchTeamsResponse struct {
+	Teams    []TeamListing
+	Page     int
+	PageSize int
+	Total    int
+}
+
+// RegenerateInviteRequest sets the limits of a new invite code.  Zero values mean no expiry / unlimited uses.
+type RegenerateInviteRequest struct {
+	ExpiresInHours int
+	MaxUses        int
+}
+
+func sanitizeTeam(team *database.DBTeam) {
+	team.Name = strings.TrimSpace(sanitize.Scripts(team.Name))
+	team.Visibility = strings.ToLower(strings.TrimSpace(team.Visibility))
+	team.Timezone = strings.TrimSpace(sanitize.Scripts(team.Timezone))
+	team.Technologies = sanitize.Scripts(team.Technologies)
+	team.Availability = sanitize.Scripts(team.Availability)
+	team.Description = sanitize.Scripts(team.Description)
+
+	// never nil, the column doesn't allow NULL
+	wantedRoles := []string{}
+	for _, role := range team.WantedRoles {
+		role = strings.TrimSpace(sanitize.Scripts(role))
+		if role != "" {
+			wantedRoles = append(wantedRoles, role)
+		}
 	}
-	hash := md5.Sum([]byte(teamName + "." + randNum.String()))
-	return hex.EncodeToString(hash[:7]), nil
+	team.WantedRoles = wantedRoles
 }
 
-func (server *Server) signupsAllowed(eventId string) bool {
-	statusCode, err := database.GetEventStatusCode(convert.StringToUUID(eventId))
+// validateTeamName checks the name rules and that no other team in the event uses the name.
+func (server *Server) validateTeamName(team database.DBTeam, response *models.FormResponse) {
+	errorCount := len(response.Errors)
+	server.validateName("Name", team.Name, minTeamNameLength, maxTeamNameLength, response)
+	if len(response.Errors) > errorCount {
+		return
+	}
+
+	taken, err := database.TeamNameTaken(team.EventId, team.Name, team.Id)
 	if err != nil {
-		logger.Error("Error in GetEventStatusCode: %v+", err)
-		return false
+		// the unique index still catches it when the team is saved
+		logger.Error("validateTeamName: TeamNameTaken error: %v", err)
+	} else if taken {
+		response.AddError("Name", "already taken by another team in this event")
 	}
+}
 
-	if statusCode == "SIGNUP" || statusCode == "STARTED" {
+// teamNameConflict adds the form error for a save that lost a race for the team name.
+// Returns true if err was that conflict.
+func teamNameConflict(err error, response *models.FormResponse) bool {
+	if database.IsUniqueViolation(err, database.TeamNameConstraint) {
+		response.AddError("Name", "already taken by another team in this event")
 		return true
-	} else {
-		return false
 	}
+	return false
+}
+
+func (server *Server) validateTeam(team database.DBTeam, response *models.FormResponse) {
+	server.validateTeamName(team, response)
+
+	if team.Timezone != "" && !validTimezone(team.Timezone) {
+		response.AddError("Timezone", "must be an IANA timezone such as Europe/Berlin")
+	}
+
+	if team.Visibility != database.TeamVisibilityPublic && team.Visibility != database.TeamVisibilityPrivate {
+		response.AddError("Visibility", "must be public or private")
+	}
+
+	if len(team.WantedRoles) > maxWantedRoles {
+		response.AddError("WantedRoles", fmt.Sprintf("at most %d roles", maxWantedRoles))
+	}
+	for _, role := range team.WantedRoles {
+		if len(role) > maxWantedRoleLength {
+			response.AddError("WantedRoles", fmt.Sprintf("roles must be at most %d characters", maxWantedRoleLength))
+			break
+		}
+	}
+}
+
+const (
+	defaultTeamPageSize = 20
+	maxTeamPageSize     = 100
+	maxWantedRoles      = 10
+	maxWantedRoleLength = 32
+)
+
+// inviteCodeAttempts is how many times a new invite code is generated before giving up on collisions
+const inviteCodeAttempts = 5
+
+// GenerateInviteCode returns a random, URL safe invite code
+func GenerateInviteCode() (string, error) {
+	code := make([]byte, 16)
+	_, err := rand.Read(code)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(code), nil
 }
 
-func (server *Server) GetAllTeams(ctx *gin.Context) {
-	teams, err := database.GetTeams()
-	if err == nil {
-		ctx.JSON(http.StatusOK, teams)
+// createTeamWithInviteCode inserts the team with a new invite code, retrying if the code collides with an
+// existing one.  Each attempt runs in a savepoint, so a failed insert, e.g. a taken name, leaves a surrounding
+// transaction usable.
+func createTeamWithInviteCode(tx database.Querier, team database.DBTeam) (pgtype.UUID, error) {
+	var teamUUID pgtype.UUID
+	var err error
+	for attempt := 0; attempt < inviteCodeAttempts; attempt++ {
+		team.InviteCode, err = GenerateInviteCode()
+		if err != nil {
+			return teamUUID, err
+		}
+		err = pgx.BeginFunc(context.Background(), tx, func(savepoint pgx.Tx) error {
+			var err error
+			teamUUID, err = database.CreateTeamTx(savepoint, team)
+			return err
+		})
+		if !database.IsUniqueViolation(err, "idx_team_invite_code") {
+			break
+		}
+	}
+	return teamUUID, err
+}
+
+// signupsAllowed reports whether teams can be created and joined in the event, following its roster lock policy.
+func (server *Server) signupsAllowed(eventId string) bool {
+	event, statusCode, ok := loadEventForRoster(convert.StringToUUID(eventId))
+	return ok && !eventRosterLocked(event, statusCode, time.Now())
+}
+
+// SearchTeams lists the public teams of an event a page at a time.  The active event is searched unless an
+// eventId is given.  Filters are passed as query parameters:
+//
+//	q, technologies (comma separated), availability, role, looking (true/false),
+//	minOpenSlots, minUtcOffset, maxUtcOffset (hours), page, pageSize
+func (server *Server) SearchTeams(ctx *gin.Context) {
+	response := models.NewFormResponse()
+	search := database.TeamSearch{
+		Query:        strings.TrimSpace(ctx.Query("q")),
+		Availability: strings.TrimSpace(ctx.Query("availability")),
+		WantedRole:   strings.TrimSpace(ctx.Query("role")),
+		Page:         parseIntQuery(ctx, "page", 1, &response),
+		PageSize:     parseIntQuery(ctx, "pageSize", defaultTeamPageSize, &response),
+		MinOpenSlots: parseIntQuery(ctx, "minOpenSlots", 0, &response),
+		MinUTCOffset: parseFloatQuery(ctx, "minUtcOffset", &response),
+		MaxUTCOffset: parseFloatQuery(ctx, "maxUtcOffset", &response),
+	}
+
+	for _, technology := range strings.Split(ctx.Query("technologies"), ",") {
+		technology = strings.TrimSpace(technology)
+		if technology != "" {
+			search.Technologies = append(search.Technologies, technology)
+		}
+	}
+
+	if looking := ctx.Query("looking"); looking != "" {
+		value, err := strconv.ParseBool(looking)
+		if err != nil {
+			response.AddError("looking", "must be true or false")
+		}
+		search.LookingForMembers = &value
+	}
+
+	if search.Page < 1 {
+		response.AddError("page", "must be at least 1")
+	}
+	if search.PageSize < 1 || search.PageSize > maxTeamPageSize {
+		response.AddError("pageSize", fmt.Sprintf("must be between 1 and %d", maxTeamPageSize))
+	}
+
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if eventId := ctx.Query("eventId"); eventId != "" {
+		search.EventId = convert.StringToUUID(eventId)
 	} else {
+		event, err := database.GetActiveEvent()
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				ctx.Status(http.StatusNoContent)
+			} else {
+				ctx.Status(http.StatusInternalServerError)
+			}
+			return
+		}
+		search.EventId = event.Id
+	}
+
+	teams, total, err := database.SearchTeams(search)
+	if err != nil {
 		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	listings := make([]TeamListing, 0, len(teams))
+	for _, team := range teams {
+		listings = append(listings, newTeamListing(team))
 	}
+
+	ctx.JSON(http.StatusOK, SearchTeamsResponse{
+		Teams:    listings,
+		Page:     search.Page,
+		PageSize: search.PageSize,
+		Total:    total,
+	})
+}
+
+// parseIntQuery reads an optional integer query parameter, adding a form error if it isn't a number
+func parseIntQuery(ctx *gin.Context, name string, defaultValue int, response *models.FormResponse) int {
+	value := ctx.Query(name)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		response.AddError(name, "must be a number")
+		return defaultValue
+	}
+	return result
+}
+
+// parseFloatQuery reads an optional numeric query parameter, adding a form error if it isn't a number
+func parseFloatQuery(ctx *gin.Context, name string, response *models.FormResponse) *float64 {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		response.AddError(name, "must be a number")
+		return nil
+	}
+	return &result
 }
 
 func (server *Server) GetUserTeams(ctx *gin.Context) {
@@ -95,82 +315,153 @@ func (server *Server) GetUserTeams(ctx *gin.Context) {
 }
 
 // stepp 4: GET team info
-// purpose is to construct the DBTeamMemberInfo
+// purpose is to construct the GetTeamResponse.  Private teams are only visible to their members and organizers.
 func (server *Server) GetTeamInfo(ctx *gin.Context) {
 	id := convert.StringToUUID(ctx.Param("id"))
-
-	var teamResponse GetTeamResponse
-	var team database.DBTeam
-	var event database.DBEvent
-	var members *[]database.DBTeamMemberInfo //user info based on teamId
+	viewerId := server.OptionalSessionUserId(ctx)
 
 	team, err := database.GetTeam(id)
 	if err != nil {
-		logger.Error("failed to get team: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get team: %v", err)})
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			logger.Error("failed to get team: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
 		return
 	}
 
-	event, err = database.GetEvent(team.EventId)
+	teamResponse, err := buildTeamResponse(team, viewerId)
 	if err != nil {
-		logger.Error("failed to get event: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get event: %v", err)})
+		logger.Error("GetTeamInfo: %v", err)
+		ctx.Status(http.StatusInternalServerError)
 		return
 	}
 
-	members, err = database.GetMembersByTeamId(team.Id)
-	if err != nil {
-		logger.Error("failed to get event: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get members: %v", err)})
-		return
+	if team.Visibility != database.TeamVisibilityPublic && !teamResponse.isMember {
+		isOrganizer := false
+		if viewerId.Valid {
+			isOrganizer, err = server.UserIsEventOrganizer(viewerId, team.EventId)
+		}
+		if err != nil || !isOrganizer {
+			// don't reveal that the private team exists
+			ctx.Status(http.StatusNotFound)
+			return
+		}
 	}
 
-	// attach all 3 structures to GetTeamResponse --> nested structs turn into nested JSON (with ctx.JSON)
-	teamResponse.Team = &team
-	teamResponse.Event = &event
-	teamResponse.Members = members
+	if teamResponse.isMember {
+		members, err := database.GetTeamAvailability(team.Id)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		overlap := teamOverlap(members, time.Now())
+		teamResponse.Overlap = &overlap
+	}
 
 	ctx.JSON(http.StatusOK, teamResponse)
 }
 
+// GetTeamInfoByInviteCode shows the team behind an invite, private or not, since the invite code was shared.
 func (server *Server) GetTeamInfoByInviteCode(ctx *gin.Context) {
 	inviteCode := ctx.Param("invitecode")
-	fmt.Println("\n===server getteam by invite code: ", inviteCode)
-
-	var teamResponse GetTeamResponse
-	var team database.DBTeam
-	var event database.DBEvent
-	var members *[]database.DBTeamMemberInfo //user info based on teamId
 
 	team, err := database.GetTeamByInvite(inviteCode)
 	if err != nil {
-		logger.Error("failed to get team: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get team: %v", err)})
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			logger.Error("failed to get team: %v", err)
+			ctx.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	teamResponse, err := buildTeamResponse(team, server.OptionalSessionUserId(ctx))
+	if err != nil {
+		logger.Error("GetTeamInfoByInviteCode: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, teamResponse)
+}
+
+// JoinTeamByInviteCode adds the session user to the team matching the invite code as a regular member.
+func (server *Server) JoinTeamByInviteCode(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	userId := session.Get("userId")
+	if userId == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+	userUUID := convert.StringToUUID(userId.(string))
+
+	user, err := database.GetUser(userUUID)
+	if err != nil {
+		logger.Error("JoinTeamByInviteCode: GetUser error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// banned (or otherwise inactive) accounts can't join teams
+	if user.AccountStatus != database.AccountActive {
+		logger.Error("JoinTeamByInviteCode: user %v has account status %s", userId, user.AccountStatus)
+		ctx.Status(http.StatusForbidden)
 		return
-	} else {
-		fmt.Println("TEST if GetTeamByInvite was SUCCESS: ", err, " + ", team)
 	}
 
-	event, err = database.GetEvent(team.EventId)
+	team, err := database.GetTeamByInvite(ctx.Param("invitecode"))
 	if err != nil {
-		logger.Error("failed to get event: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get event: %v", err)})
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
 		return
 	}
 
-	members, err = database.GetMembersByTeamId(team.Id)
+	if server.rosterLocked(team) || team.Locked {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	isMember, err := database.IsTeamMember(team.Id, userUUID)
 	if err != nil {
-		logger.Error("failed to get event: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to get members: %v", err)})
+		logger.Error("JoinTeamByInviteCode: IsTeamMember error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	if isMember {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "already a member of this team"})
+		return
+	}
+
+	if !server.VerifyCanJoinEvent(ctx, userUUID, team.EventId) || !server.VerifyTeamHasRoom(ctx, team.Id) {
+		return
+	}
+
+	team, err = database.JoinTeamWithInvite(team.Id, team.InviteCode, userUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the code expired or ran out of uses since it was looked up
+			ctx.Status(http.StatusNotFound)
+		} else {
+			logger.Error("JoinTeamWithInvite error: %v for user %v", err, userId)
+			addTeamMemberError(ctx, err)
+		}
 		return
 	}
 
-	// attach all 3 structures to GetTeamResponse --> nested structs turn into nested JSON (with ctx.JSON)
-	teamResponse.Team = &team
-	teamResponse.Event = &event
-	teamResponse.Members = members
+	teamResponse, err := buildTeamResponse(team, userUUID)
+	if err != nil {
+		logger.Error("JoinTeamByInviteCode: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
 
-	fmt.Println(teamResponse)
+	logger.Info("User %v joined team %v", userId, convert.UUIDToString(team.Id))
 	ctx.JSON(http.StatusOK, teamResponse)
 }
 
@@ -203,6 +494,10 @@ func (server *Server) CreateTeam(ctx *gin.Context) {
 		return
 	}
 
+	if !server.VerifyCanJoinEvent(ctx, convert.StringToUUID(strUserId), convert.StringToUUID(teamReq.EventId)) {
+		return
+	}
+
 	// CONVERT teamReq to team
 	team.EventId = convert.StringToUUID(teamReq.EventId)
 	team.Name = teamReq.Name
@@ -211,74 +506,265 @@ func (server *Server) CreateTeam(ctx *gin.Context) {
 	team.Visibility = teamReq.Visibility
 	team.Technologies = teamReq.Technologies
 	team.Timezone = teamReq.Timezone
+	team.LookingForMembers = teamReq.LookingForMembers
+	team.WantedRoles = teamReq.WantedRoles
+	sanitizeTeam(&team)
+	names := teamTagNames(teamReq.Tags, teamReq.Technologies)
+
+	response := models.NewFormResponse()
+	server.validateTeam(team, &response)
+	validateTags(names, &response)
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// INSERTS TEAM into DB with its owner and tags, generating a new invite code if it happens to collide with an
+	// existing one
+	var teamUUID pgtype.UUID
+	err = database.WithTransaction(func(tx pgx.Tx) error {
+		tags, err := database.ResolveTagsTx(tx, names)
+		if err != nil {
+			return err
+		}
+		// keep the free text in step with the tags for older clients
+		team.Technologies = strings.Join(tagNames(tags), ", ")
 
-	md5code, err := MD5HashCode(team.Name)
+		if teamUUID, err = createTeamWithInviteCode(tx, team); err != nil {
+			return err
+		}
+		if _, err = database.AddTeamMemberTx(tx, convert.StringToUUID(strUserId), teamUUID,
+			database.TeamRoleOwner); err != nil {
+			return err
+		}
+		return database.SetTeamTagsTx(tx, teamUUID, tags)
+	})
+	if teamNameConflict(err, &response) {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	} else if err != nil {
+		logger.Error("CreateTeam error: %v for user %s", err, strUserId)
+		addTeamMemberError(ctx, err)
+		return
+	}
+
+	logger.Info("User %s created team %v", strUserId, convert.UUIDToString(teamUUID))
+	ctx.JSON(http.StatusCreated, map[string]pgtype.UUID{
+		"id": teamUUID,
+	})
+}
+
+// VerifyTeamManager checks that the user's team role has the permission or that they organize the team's event.
+// Only organizers can manage locked teams.
+// Appropriate HTTP responses are set automatically.
+// Returns true if the user may manage the team, false otherwise.
+func (server *Server) VerifyTeamManager(ctx *gin.Context, team database.DBTeam, userId pgtype.UUID,
+	permission TeamPermission) bool {
+	member, err := database.GetTeamMember(team.Id, userId)
+	if err == nil && teamRoleHasPermission(member.TeamRole, permission) && !team.Locked {
+		return true
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("VerifyTeamManager: GetTeamMember error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return false
+	}
+
+	isOrganizer, err := server.UserIsEventOrganizer(userId, team.EventId)
 	if err != nil {
-		logger.Error("Error - Md5HashCode failed", err)
+		logger.Error("VerifyTeamManager: UserIsEventOrganizer error: %v", err)
 		ctx.Status(http.StatusInternalServerError)
+		return false
+	}
+	if !isOrganizer {
+		logger.Error("VerifyTeamManager: unauthorized user: %v", convert.UUIDToString(userId))
+		ctx.Status(http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (server *Server) UpdateTeam(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
 		return
 	}
-	team.InviteCode = md5code
 
-	fmt.Printf("%+v", team)
-	// INSERTS TEAM into DB
-	// PART 1/2 DONE
-	teamUUID, err := database.CreateTeam(team)
+	team, err := database.GetTeam(convert.StringToUUID(ctx.Param("id")))
 	if err != nil {
-		logger.Error("Error trying to CreateTeam(team)")
-		ctx.Status(http.StatusBadRequest)
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// PART 2/2 DONE
-	// construct TeamMember
-	_, err = database.AddTeamMember(convert.StringToUUID(strUserId), teamUUID, "owner")
+	var request UpdateTeamRequest
+	if !server.VerifyTeamManager(ctx, team, userId, PermissionEditTeam) ||
+		!server.DeserializeRequest(ctx, &request) {
+		return
+	}
 
-	if err == nil {
-		fmt.Println("Successfully added team member")
-		ctx.JSON(http.StatusCreated, map[string]pgtype.UUID{
-			"id": teamUUID,
-		})
-	} else {
-		fmt.Println(err)
-		logger.Error("AddTeamMember error: %v for user %s", err, strUserId)
-		ctx.Status(http.StatusInternalServerError)
+	// only copy the editable fields, the event and invite code can't be changed by clients
+	team.Name = request.Name
+	team.Visibility = request.Visibility
+	team.Timezone = request.Timezone
+	team.Technologies = request.Technologies
+	team.Availability = request.Availability
+	team.Description = request.Description
+	team.LookingForMembers = request.LookingForMembers
+	team.WantedRoles = request.WantedRoles
+	names := teamTagNames(request.Tags, request.Technologies)
+
+	response := models.NewFormResponse()
+
+	sanitizeTeam(&team)
+	server.validateTeam(team, &response)
+	validateTags(names, &response)
+
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
 		return
 	}
+
+	var tags []database.DBTag
+	err = database.WithTransaction(func(tx pgx.Tx) error {
+		var err error
+		if tags, err = database.ResolveTagsTx(tx, names); err != nil {
+			return err
+		}
+		team.Technologies = strings.Join(tagNames(tags), ", ")
+
+		if team, err = database.UpdateTeamTx(tx, team); err != nil {
+			return err
+		}
+		return database.SetTeamTagsTx(tx, team.Id, tags)
+	})
+	if teamNameConflict(err, &response) {
+		ctx.JSON(http.StatusBadRequest, response)
+	} else if err != nil {
+		logger.Error("Error calling database.UpdateTeam: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+	} else {
+		logger.Info("User %v updated Team %v", convert.UUIDToString(userId), convert.UUIDToString(team.Id))
+		// organizers can edit teams they aren't on, they don't get the invite code
+		isMember, _ := database.IsTeamMember(team.Id, userId)
+		teamInfo := newTeamInfo(team, isMember)
+		teamInfo.Tags = tagNames(tags)
+		response.Data = teamInfo
+		ctx.JSON(http.StatusOK, response)
+	}
 }
 
-func (server *Server) UpdateTeam(ctx *gin.Context) {
-	session := sessions.Default(ctx)
-	userId := session.Get("userId")
-	if userId != nil {
-		var team database.DBTeam
-		err := ctx.ShouldBindJSON(&team)
-		if err != nil {
-			logger.Error("UpdateEvent Request ShouldBindJSON error: %v", err)
-			ctx.Status(http.StatusBadRequest)
-			return
+// RegenerateInviteCode replaces the team's invite code, revoking the previous one.
+func (server *Server) RegenerateInviteCode(ctx *gin.Context) {
+	userId, ok := server.GetSessionUserId(ctx)
+	if !ok {
+		return
+	}
+
+	team, err := database.GetTeam(convert.StringToUUID(ctx.Param("id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ctx.Status(http.StatusNotFound)
+		} else {
+			ctx.Status(http.StatusInternalServerError)
 		}
-		team, err = database.UpdateTeam(team)
+		return
+	}
+
+	var request RegenerateInviteRequest
+	if !server.VerifyTeamManager(ctx, team, userId, PermissionManageMembers) ||
+		!server.DeserializeRequest(ctx, &request) {
+		return
+	}
+
+	response := models.NewFormResponse()
+	if request.ExpiresInHours < 0 {
+		response.AddError("ExpiresInHours", "must not be negative")
+	}
+	if request.MaxUses < 0 {
+		response.AddError("MaxUses", "must not be negative")
+	}
+	if len(response.Errors) > 0 {
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if request.ExpiresInHours > 0 {
+		expiresAt = pgtype.Timestamptz{
+			Time:  time.Now().UTC().Add(time.Duration(request.ExpiresInHours) * time.Hour),
+			Valid: true,
+		}
+	}
+	var maxUses *int
+	if request.MaxUses > 0 {
+		maxUses = &request.MaxUses
+	}
+
+	for attempt := 0; attempt < inviteCodeAttempts; attempt++ {
+		var inviteCode string
+		inviteCode, err = GenerateInviteCode()
 		if err != nil {
-			logger.Error("Error calling database.UpdateEvent: %v", err)
+			logger.Error("RegenerateInviteCode: GenerateInviteCode error: %v", err)
 			ctx.Status(http.StatusInternalServerError)
-		} else {
-			ctx.JSON(http.StatusOK, team)
+			return
 		}
-	} else {
-		ctx.Status(http.StatusUnauthorized)
+		team, err = database.SetTeamInviteCode(team.Id, inviteCode, expiresAt, maxUses)
+		if !database.IsUniqueViolation(err, "idx_team_invite_code") {
+			break
+		}
+	}
+	if err != nil {
+		logger.Error("RegenerateInviteCode: SetTeamInviteCode error: %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("User %v regenerated the invite code for team %v", convert.UUIDToString(userId), convert.UUIDToString(team.Id))
+	// organizers can regenerate the code of teams they aren't on, only managing members get the new one
+	member, err := database.GetTeamMember(team.Id, userId)
+	canSeeInvite := err == nil && teamRoleHasPermission(member.TeamRole, PermissionManageMembers)
+	tags, err := database.GetTeamTags(team.Id)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
 	}
+	teamInfo := newTeamInfo(team, canSeeInvite)
+	teamInfo.Tags = tagNames(tags)
+	response.Data = teamInfo
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (server *Server) SetupTeamRoutes() {
 	group := server.Gin.Group("/team")
 	{
 		group.POST("/", server.CreateTeam)
-		group.GET("/", server.GetAllTeams)
+		group.GET("/", server.SearchTeams)
 		group.GET("/:id", server.GetTeamInfo)
 		group.GET("/invite/:invitecode", server.GetTeamInfoByInviteCode)
-		// group.PUT("/:id", server.UpdateTeam)
-		// Step 3: Post Team Data API
+		group.POST("/invite/:invitecode/join", server.JoinTeamByInviteCode)
+		group.POST("/:id/leave", server.LeaveTeam)
+		group.DELETE("/:id/member/:userId", server.RemoveMember)
+		group.PUT("/:id/member/:userId/role", server.ChangeMemberRole)
+		group.PUT("/:id/owner", server.TransferOwnership)
+		group.POST("/:id/invite_code", server.RegenerateInviteCode)
+		group.POST("/:id/join_requests", server.PostJoinRequest)
+		group.GET("/:id/join_requests", server.GetJoinRequests)
+		group.PUT("/:id/join_requests/:requestId/approve", server.ApproveJoinRequest)
+		group.PUT("/:id/join_requests/:requestId/decline", server.DeclineJoinRequest)
+		group.POST("/:id/invitations", server.PostTeamInvitation)
+		group.GET("/:id/invitations", server.GetTeamInvitations)
+		group.DELETE("/:id/invitations/:invitationId", server.DeleteTeamInvitation)
+		group.GET("/:id/messages", server.GetTeamMessages)
+		group.POST("/:id/messages", server.PostTeamMessage)
+		group.PUT("/:id/messages/:messageId", server.PutTeamMessage)
+		group.DELETE("/:id/messages/:messageId", server.DeleteTeamMessage)
+		group.PUT("/:id/messages/:messageId/pin", server.PutTeamMessagePin)
+		group.PUT("/:id", server.UpdateTeam)
 	}
 
 	server.Gin.GET("/teams", server.GetUserTeams) // I think this works rofl
@@ -3,17 +3,32 @@ package server
 import (
 	"codejam.io/database"
 	"codejam.io/server/models"
+	"fmt"
 	"github.com/emicklei/pgtalk/convert"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"math/rand/v2"
 	"net/http"
 	"strings"
 )
 
-func validateDisplayName(displayName string, response *models.FormResponse) {
-	if displayName == "" {
-		response.AddError("DisplayName", "required")
+// generatedDisplayName starts the names given to users whose provider account name isn't allowed
+const generatedDisplayName = "Participant"
+
+func (server *Server) validateDisplayName(displayName string, response *models.FormResponse) {
+	server.validateName("DisplayName", displayName, minDisplayNameLength, maxDisplayNameLength, response)
+}
+
+// signUpDisplayName is the display name of a user signing up with a provider account.  The account's name is used
+// when it passes the same checks as names users pick, otherwise a neutral name is generated that they can change.
+func (server *Server) signUpDisplayName(serviceUserName string) string {
+	response := models.NewFormResponse()
+	name := strings.TrimSpace(serviceUserName)
+	server.validateDisplayName(name, &response)
+	if len(response.Errors) > 0 {
+		return fmt.Sprintf("%s %04d", generatedDisplayName, rand.IntN(10000))
 	}
+	return name
 }
 
 func (server *Server) GetUser(ctx *gin.Context) {
@@ -65,7 +80,7 @@ func (server *Server) PutProfile(ctx *gin.Context) {
 		request.DisplayName = strings.Trim(request.DisplayName, " ")
 
 		// Perform validation
-		validateDisplayName(request.DisplayName, &response)
+		server.validateDisplayName(request.DisplayName, &response)
 		if len(response.Errors) > 0 {
 			logger.Error("Validation Error: %v+", user)
 			ctx.JSON(http.StatusBadRequest, response)
@@ -112,6 +127,15 @@ func (server *Server) SetupUserRoutes() {
 		group.GET("/", server.GetUser)
 		group.PUT("/profile", server.PutProfile)
 		group.GET("/logout", server.Logout)
+		group.GET("/notifications", server.GetNotifications)
+		group.PUT("/notifications/:id/read", server.PutNotificationRead)
+		group.GET("/availability", server.GetAvailability)
+		group.PUT("/availability", server.PutAvailability)
+		group.GET("/invitations", server.GetMyInvitations)
+		group.PUT("/invitations/:id/accept", server.AcceptInvitation)
+		group.PUT("/invitations/:id/decline", server.DeclineInvitation)
+		group.GET("/identities", server.GetIdentities)
+		group.DELETE("/identities/:id", server.DeleteIdentity)
 	}
 
 }
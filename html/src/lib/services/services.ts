@@ -141,14 +141,11 @@ export async function getTeamByInvite(inviteCode: string) {
 }
 
 
-// any one who has the link joinTeam connects to can join the team.
-// make sure invite_code matches 
-export async function joinTeam(team: CodeJamTeam, userId: string, invite_code: string) {
-    // making a post to team_members
-    return await fetch(baseApiUrl + "/team/" + invite_code,
+// anyone who has the invite link can join the team as a member.
+export async function joinTeam(inviteCode: string) {
+    return await fetch(baseApiUrl + "/team/invite/" + inviteCode + "/join",
         {
-            method: "POST",
-            body: JSON.stringify({ team, userId, invite_code })
+            method: "POST"
         }
     )
 }